@@ -0,0 +1,208 @@
+//Package clock abstracts the passing of time, so components
+//driven by time (schedulers, monitors, effective dated
+//activations) can run against a simulated clock in tests,
+//where months can pass in milliseconds.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Clock tells the time and produces tickers
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a ticker firing every d
+	NewTicker(d time.Duration) Ticker
+}
+
+//Ticker delivers ticks on a channel until stopped
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+//Real is the Clock of the wall time
+type Real struct{}
+
+//Now implementation of Clock
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+//NewTicker implementation of Clock
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+//realTicker adapts a time.Ticker to Ticker
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+//Simulated is a Clock that only moves when advanced. It
+//is safe for concurrent use
+type Simulated struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*simulatedTicker
+}
+
+//NewSimulated returns a simulated clock set to start
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+//Now implementation of Clock
+func (s *Simulated) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.now
+}
+
+//NewTicker implementation of Clock. Like time.NewTicker,
+//it panics if d is not positive
+func (s *Simulated) NewTicker(d time.Duration) Ticker {
+
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := &simulatedTicker{
+		clock:  s,
+		period: d,
+		next:   s.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	s.tickers = append(s.tickers, t)
+
+	return t
+}
+
+//Advance moves the clock forward by d, firing every tick
+//that falls in between, in chronological order. Like a
+//time.Ticker, a tick is dropped if the previous one has
+//not been received yet
+func (s *Simulated) Advance(d time.Duration) {
+	s.AdvanceTo(s.Now().Add(d))
+}
+
+//AdvanceTo moves the clock forward to t, see Advance
+func (s *Simulated) AdvanceTo(t time.Time) {
+
+	for {
+		s.mu.Lock()
+		due := s.dueTickers(t)
+		if len(due) == 0 {
+			if t.After(s.now) {
+				s.now = t
+			}
+			s.mu.Unlock()
+			return
+		}
+
+		ticker := due[0]
+		s.now = ticker.next
+		tick := s.now
+		ticker.next = ticker.next.Add(ticker.period)
+		s.mu.Unlock()
+
+		select {
+		case ticker.c <- tick:
+		default:
+		}
+	}
+}
+
+//dueTickers returns the running tickers due up to t,
+//the earliest first. It expects the lock held
+func (s *Simulated) dueTickers(t time.Time) []*simulatedTicker {
+
+	due := make([]*simulatedTicker, 0)
+	for _, ticker := range s.tickers {
+		if !ticker.next.After(t) {
+			due = append(due, ticker)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].next.Before(due[j].next)
+	})
+
+	return due
+}
+
+//simulatedTicker is a Ticker of a Simulated clock
+type simulatedTicker struct {
+	clock  *Simulated
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *simulatedTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *simulatedTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+//current is the package level clock, used by
+//components that are not given one explicitly
+var current = struct {
+	sync.RWMutex
+	clock Clock
+}{clock: Real{}}
+
+//Default returns the package level clock, the real
+//one unless replaced with Set
+func Default() Clock {
+	current.RLock()
+	defer current.RUnlock()
+
+	return current.clock
+}
+
+//Set replaces the package level clock, typically with a
+//Simulated one in end to end tests, and returns a function
+//restoring the previous clock
+func Set(c Clock) (restore func()) {
+	current.Lock()
+	defer current.Unlock()
+
+	previous := current.clock
+	current.clock = c
+
+	return func() {
+		current.Lock()
+		defer current.Unlock()
+		current.clock = previous
+	}
+}
+
+//Now returns the time of the package level clock
+func Now() time.Time {
+	return Default().Now()
+}
@@ -0,0 +1,97 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulatedAdvance(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewSimulated(start)
+
+	daily := c.NewTicker(24 * time.Hour)
+	defer daily.Stop()
+
+	c.Advance(12 * time.Hour)
+	select {
+	case tick := <-daily.C():
+		t.Fatalf("unexpected tick at %v", tick)
+	default:
+	}
+
+	c.Advance(12 * time.Hour)
+	if tick := <-daily.C(); !tick.Equal(start.Add(24 * time.Hour)) {
+		t.Errorf("unexpected tick at %v", tick)
+	}
+	if !c.Now().Equal(start.Add(24 * time.Hour)) {
+		t.Errorf("unexpected now %v", c.Now())
+	}
+
+	// ticks that are not received are dropped
+	c.Advance(30 * 24 * time.Hour)
+	if tick := <-daily.C(); !tick.Equal(start.Add(2 * 24 * time.Hour)) {
+		t.Errorf("expected the first pending tick, got %v", tick)
+	}
+	if !c.Now().Equal(start.Add(31 * 24 * time.Hour)) {
+		t.Errorf("unexpected now %v", c.Now())
+	}
+}
+
+func TestSimulatedStop(t *testing.T) {
+
+	c := NewSimulated(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	ticker := c.NewTicker(time.Hour)
+	ticker.Stop()
+
+	c.Advance(2 * time.Hour)
+	select {
+	case tick := <-ticker.C():
+		t.Errorf("unexpected tick at %v from a stopped ticker", tick)
+	default:
+	}
+}
+
+func TestReal(t *testing.T) {
+
+	var c Clock = Real{}
+	if time.Since(c.Now()) > time.Second {
+		t.Error("real clock is off")
+	}
+
+	ticker := c.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	<-ticker.C()
+}
+
+func TestSet(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	simulated := NewSimulated(start)
+
+	restore := Set(simulated)
+	if !Now().Equal(start) {
+		t.Errorf("expected the simulated time, got %v", Now())
+	}
+
+	simulated.Advance(time.Hour)
+	if !Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the advanced time, got %v", Now())
+	}
+
+	restore()
+	if _, ok := Default().(Real); !ok {
+		t.Errorf("expected the real clock to be restored, got %T", Default())
+	}
+}
+
+func TestSimulatedTickerNonPositive(t *testing.T) {
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a zero interval")
+		}
+	}()
+
+	NewSimulated(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)).NewTicker(0)
+}
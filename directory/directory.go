@@ -0,0 +1,152 @@
+//Package directory generates a public, read only people
+//directory from a snapshot of the organization, holding only
+//what is safe to publish on an intranet people finder.
+package directory
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//Entry is the public record of a person
+type Entry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// a reference to the photo of the person, such
+	// as a URL, never the image itself
+	Photo     string     `json:"photo,omitempty"`
+	Positions []Position `json:"positions"`
+	// the attributes of the person that were
+	// allowed by Generator.PublicAttributes
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+//Position is a position held by a person,
+//as shown in the directory
+type Position struct {
+	Title    string `json:"title"`
+	UnitID   string `json:"unitId"`
+	UnitName string `json:"unitName"`
+	// the names of the units above, from the top
+	// of the hierarchy down to the parent unit
+	UnitPath []string `json:"unitPath,omitempty"`
+	// how long the person has held the position at
+	// the pit of the snapshot, like "1 year, 2 months"
+	Tenure string `json:"tenure"`
+}
+
+//Generator builds the directory. Nothing but the names,
+//titles and units is published, unless allowed here
+type Generator struct {
+	// the attribute of a person holding the
+	// reference to their photo, none if empty
+	PhotoAttribute string
+	// the attributes of a person that may be
+	// published, such as "email" or "office"
+	PublicAttributes []string
+	// how tenures are humanized, in English
+	// with up to two units when not set
+	Tenure domain.HumanizeOptions
+}
+
+//Generate returns an entry for every person holding a
+//position in the snapshot, sorted by name and then by ID.
+//Attributes are read as they were at the pit of the snapshot
+//for TemporalAttributeBearers
+func (g Generator) Generate(snapshot *domain.OrgSnapshot) []Entry {
+
+	entries := make(map[*domain.Person]*Entry)
+	snapshot.Walk(func(u *domain.SnapshotUnit) bool {
+		for _, p := range u.Positions() {
+			if p.Holder == nil {
+				continue
+			}
+			entry, ok := entries[p.Holder]
+			if !ok {
+				entry = g.entry(p.Holder, snapshot.At)
+				entries[p.Holder] = entry
+			}
+			entry.Positions = append(entry.Positions, Position{
+				Title:    p.Position.Title,
+				UnitID:   u.Unit.ID,
+				UnitName: u.Unit.Name,
+				UnitPath: unitPath(u),
+				Tenure:   domain.FormatTenure(p.Assignment.ExistentFrom(), snapshot.At, g.Tenure),
+			})
+		}
+		return true
+	})
+
+	directory := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		directory = append(directory, *entry)
+	}
+	sort.Slice(directory, func(i, j int) bool {
+		if directory[i].Name != directory[j].Name {
+			return directory[i].Name < directory[j].Name
+		}
+		return directory[i].ID < directory[j].ID
+	})
+
+	return directory
+}
+
+//WriteJSON writes the directory generated from
+//snapshot to w as a JSON array of entries
+func (g Generator) WriteJSON(w io.Writer, snapshot *domain.OrgSnapshot) error {
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(g.Generate(snapshot))
+}
+
+//entry creates the entry of person, without positions
+func (g Generator) entry(person *domain.Person, at time.Time) *Entry {
+
+	entry := &Entry{ID: person.ID, Name: person.Name}
+	if photo, ok := attributeAt(person, g.PhotoAttribute, at).(string); ok {
+		entry.Photo = photo
+	}
+	for _, name := range g.PublicAttributes {
+		if value := attributeAt(person, name, at); value != nil {
+			if entry.Attributes == nil {
+				entry.Attributes = make(map[string]interface{})
+			}
+			entry.Attributes[name] = value
+		}
+	}
+
+	return entry
+}
+
+//attributeAt returns the value of the attribute of b at
+//the given pit, or nil if it held none
+func attributeAt(b domain.AttributeBearer, attrName string, at time.Time) interface{} {
+
+	if attrName == "" {
+		return nil
+	}
+
+	value, err := domain.AttributeAt(b, attrName, at)
+	if err != nil {
+		return nil
+	}
+
+	return value
+}
+
+//unitPath returns the names of the units above u
+func unitPath(u *domain.SnapshotUnit) []string {
+
+	var path []string
+	for p := u.Parent(); p != nil; p = p.Parent() {
+		path = append([]string{p.Unit.Name}, path...)
+	}
+
+	return path
+}
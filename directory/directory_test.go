@@ -0,0 +1,83 @@
+package directory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+func TestGenerate(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	org, _ := domain.NewOrganization("acme", "Acme", start, domain.NilTime())
+	sales, _ := domain.NewOrgUnit(org, nil, "sales", "Sales", start, domain.NilTime())
+	emea, _ := domain.NewOrgUnit(org, sales, "emea", "EMEA", start, domain.NilTime())
+	head, _ := domain.NewPosition(sales, "head", "Head of Sales", start, domain.NilTime())
+	lead, _ := domain.NewPosition(emea, "lead", "EMEA Lead", start, domain.NilTime())
+	domain.NewPosition(emea, "rep", "Sales Rep", start, domain.NilTime())
+	ann, _ := domain.NewPerson("ann", "Ann", start, domain.NilTime())
+	bob, _ := domain.NewPerson("bob", "Bob", start, domain.NilTime())
+
+	ann.SetAttributeAt("photo", "https://photos.example.com/ann.jpg", start)
+	ann.SetAttributeAt("email", "ann@example.com", start)
+	ann.SetAttributeAt("salary", 100000, start)
+	bob.SetAttributeAt("email", "bob@example.com", start.AddDate(0, 6, 0))
+
+	// Bob leads EMEA for a year, then Ann takes over
+	var assignments domain.AssignmentCollection
+	bobLead, _ := domain.NewAssignment(bob, lead, start, start.AddDate(1, 0, 0))
+	annHead, _ := domain.NewAssignment(ann, head, start, domain.NilTime())
+	annLead, _ := domain.NewAssignment(ann, lead, start.AddDate(1, 0, 0), domain.NilTime())
+	for _, a := range []*domain.Assignment{bobLead, annHead, annLead} {
+		if err := assignments.AddAssignment(a); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	g := Generator{PhotoAttribute: "photo", PublicAttributes: []string{"email"}}
+	entries := g.Generate(org.OrgSnapshot(start.AddDate(0, 1, 0), &assignments))
+	if len(entries) != 2 || entries[0].Name != "Ann" || entries[1].Name != "Bob" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if entries[0].Photo != "https://photos.example.com/ann.jpg" || entries[0].Attributes["email"] != "ann@example.com" {
+		t.Errorf("unexpected public data %+v", entries[0])
+	}
+	if _, ok := entries[0].Attributes["salary"]; ok {
+		t.Error("expected the salary not to be published")
+	}
+	if entries[1].Attributes != nil {
+		t.Errorf("expected no email for Bob before it was set, got %v", entries[1].Attributes)
+	}
+	if p := entries[1].Positions; len(p) != 1 || p[0].Title != "EMEA Lead" || p[0].UnitID != "emea" ||
+		len(p[0].UnitPath) != 1 || p[0].UnitPath[0] != "Sales" {
+		t.Errorf("unexpected positions %+v", p)
+	}
+	if tenure := entries[1].Positions[0].Tenure; tenure != "1 month" {
+		t.Errorf("expected Bob to have led EMEA for a month, got %q", tenure)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteJSON(&buf, org.OrgSnapshot(start.AddDate(2, 0, 0), &assignments)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "salary") || strings.Contains(output, "Bob") {
+		t.Errorf("unexpected directory %s", output)
+	}
+	if strings.Count(output, `"title"`) != 2 {
+		t.Errorf("expected both positions of Ann, got %s", output)
+	}
+	if !strings.Contains(output, `"tenure": "2 years"`) || !strings.Contains(output, `"tenure": "1 year"`) {
+		t.Errorf("expected the tenures of Ann, got %s", output)
+	}
+
+	// tenures follow the options of the generator
+	g.Tenure = domain.HumanizeOptions{Locale: &domain.Greek, BusinessDays: true}
+	entries = g.Generate(org.OrgSnapshot(start.AddDate(0, 0, 7), &assignments))
+	if tenure := entries[1].Positions[0].Tenure; tenure != "5 εργάσιμες ημέρες" {
+		t.Errorf("unexpected tenure %q", tenure)
+	}
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ParseAsOf resolves a human friendly as-of expression into a
+//pit, relative to now and in the location of now. Supported are
+//
+//  - absolute dates: "2021", "2021-06", "2021-06-15" and RFC 3339
+//  - "now", "today", "yesterday", "tomorrow"
+//  - amounts in the past: "two years ago", "3 months ago", "a week ago"
+//  - period boundaries: "start of this month", "end of last quarter",
+//    "beginning of next year", for weeks, months, quarters and years
+//
+//Dates and boundaries resolve to midnight, except "end of" which
+//resolves to the last instant of the period, so entities ending
+//with it are still existent at the returned pit
+func ParseAsOf(expr string, now time.Time) (time.Time, error) {
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	switch normalized {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	for _, layout := range []string{"2006", "2006-01", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, normalized, loc); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(expr)); err == nil {
+		return t, nil
+	}
+
+	if strings.HasSuffix(normalized, " ago") {
+		return parseAgo(strings.TrimSuffix(normalized, " ago"), now, expr)
+	}
+
+	for prefix, end := range map[string]bool{"start of ": false, "beginning of ": false, "end of ": true} {
+		if strings.HasPrefix(normalized, prefix) {
+			return parseBoundary(strings.TrimPrefix(normalized, prefix), end, today, expr)
+		}
+	}
+
+	return NilTime(), fmt.Errorf("unrecognized date expression %q", expr)
+}
+
+//numberWords maps the amounts that may be spelled out
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4,
+	"five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+	"ten": 10, "eleven": 11, "twelve": 12,
+}
+
+//parseAgo resolves "<amount> <unit>" into a pit before now
+func parseAgo(amountAndUnit string, now time.Time, expr string) (time.Time, error) {
+
+	parts := strings.Split(amountAndUnit, " ")
+	if len(parts) != 2 {
+		return NilTime(), fmt.Errorf("unrecognized date expression %q", expr)
+	}
+
+	amount, ok := numberWords[parts[0]]
+	if !ok {
+		n, err := strconv.Atoi(parts[0])
+		if err != nil || n < 0 {
+			return NilTime(), fmt.Errorf("invalid amount in date expression %q", expr)
+		}
+		amount = n
+	}
+
+	switch strings.TrimSuffix(parts[1], "s") {
+	case "day":
+		return now.AddDate(0, 0, -amount), nil
+	case "week":
+		return now.AddDate(0, 0, -7*amount), nil
+	case "month":
+		return now.AddDate(0, -amount, 0), nil
+	case "quarter":
+		return now.AddDate(0, -3*amount, 0), nil
+	case "year":
+		return now.AddDate(-amount, 0, 0), nil
+	}
+
+	return NilTime(), fmt.Errorf("invalid unit in date expression %q", expr)
+}
+
+//parseBoundary resolves "<this|last|next> <unit>" into the
+//start, or the last instant if end is set, of that period
+func parseBoundary(relativeAndUnit string, end bool, today time.Time, expr string) (time.Time, error) {
+
+	parts := strings.Split(relativeAndUnit, " ")
+	if len(parts) != 2 {
+		return NilTime(), fmt.Errorf("unrecognized date expression %q", expr)
+	}
+
+	offsets := map[string]int{"this": 0, "current": 0, "last": -1, "previous": -1, "next": 1}
+	offset, ok := offsets[parts[0]]
+	if !ok {
+		return NilTime(), fmt.Errorf("unrecognized date expression %q", expr)
+	}
+
+	var start time.Time
+	var next func(time.Time) time.Time
+	switch parts[1] {
+	case "week":
+		// weeks start on Monday
+		daysSinceMonday := (int(today.Weekday()) + 6) % 7
+		start = today.AddDate(0, 0, -daysSinceMonday+7*offset)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case "month":
+		start = time.Date(today.Year(), today.Month()+time.Month(offset), 1, 0, 0, 0, 0, today.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case "quarter":
+		firstMonth := (today.Month()-1)/3*3 + 1
+		start = time.Date(today.Year(), firstMonth+time.Month(3*offset), 1, 0, 0, 0, 0, today.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(0, 3, 0) }
+	case "year":
+		start = time.Date(today.Year()+offset, 1, 1, 0, 0, 0, 0, today.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	default:
+		return NilTime(), fmt.Errorf("invalid unit in date expression %q", expr)
+	}
+
+	if end {
+		return next(start).Add(-time.Nanosecond), nil
+	}
+
+	return start, nil
+}
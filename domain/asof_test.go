@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+
+	// a Wednesday
+	now := time.Date(2021, 8, 18, 14, 30, 0, 0, time.UTC)
+	lastInstant := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	}
+
+	cases := map[string]time.Time{
+		"now":                      now,
+		"Today":                    time.Date(2021, 8, 18, 0, 0, 0, 0, time.UTC),
+		"yesterday":                time.Date(2021, 8, 17, 0, 0, 0, 0, time.UTC),
+		"2021":                     time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2021-06":                  time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		"2021-06-15":               time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC),
+		"2021-06-15T10:00:00Z":     time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC),
+		"two years ago":            time.Date(2019, 8, 18, 14, 30, 0, 0, time.UTC),
+		"3 months ago":             time.Date(2021, 5, 18, 14, 30, 0, 0, time.UTC),
+		"a week ago":               time.Date(2021, 8, 11, 14, 30, 0, 0, time.UTC),
+		"end of last quarter":      lastInstant(2021, 7, 1),
+		"start of  this quarter":   time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		"beginning of next year":   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		"end of this month":        lastInstant(2021, 9, 1),
+		"start of last month":      time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		"start of this week":       time.Date(2021, 8, 16, 0, 0, 0, 0, time.UTC),
+		"end of previous week":     lastInstant(2021, 8, 16),
+		"start of last quarter":    time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		"end of last year":         lastInstant(2021, 1, 1),
+		"start of current quarter": time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for expr, expected := range cases {
+		got, err := ParseAsOf(expr, now)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", expr, err)
+			continue
+		}
+		if !got.Equal(expected) {
+			t.Errorf("%q: expected %v, got %v", expr, expected, got)
+		}
+	}
+
+	for _, expr := range []string{"", "soon", "many years ago", "2 fortnights ago", "end of some month", "start of this decade"} {
+		if _, err := ParseAsOf(expr, now); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
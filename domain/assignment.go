@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+//ErrPositionOccupied is returned when assigning someone to
+//a position that is held by someone else at the same time
+var ErrPositionOccupied = errors.New("position is already held")
+
+//Assignment records that a person held a position
+//during an interval
+type Assignment struct {
+	lifespan
+	BaseTemporalAttributeBearer
+	Person   *Person
+	Position *Position
+}
+
+//NewAssignment creates an assignment of person to position
+//from the given pit until the given one, which may be zero.
+//Both the person and the position must exist for the whole
+//assignment, otherwise ErrOutsideParent is returned
+func NewAssignment(person *Person, position *Position, from time.Time, until time.Time) (*Assignment, error) {
+
+	span, err := newLifespan(from, until)
+	if err != nil {
+		return nil, err
+	}
+	if !span.within(person.lifespan) || !span.within(position.lifespan) {
+		return nil, ErrOutsideParent
+	}
+
+	return &Assignment{lifespan: span, Person: person, Position: position}, nil
+}
+
+//String implementation of an assignment
+func (a *Assignment) String() string {
+	return fmt.Sprintf("assignment %s to %s %s", a.Person.ID, a.Position.ID, a.lifespan)
+}
+
+//AssignmentCollection holds assignments indexed both per
+//person and per position, each index being an interval tree,
+//so the holders of a position and the positions of a person
+//can be looked up at any pit. The zero value is an empty
+//collection ready to use
+type AssignmentCollection struct {
+	byPerson   map[*Person]*TimeTrackedEntityCollection[*Assignment]
+	byPosition map[*Position]*TimeTrackedEntityCollection[*Assignment]
+}
+
+//AddAssignment adds a to the collection. A position is held
+//by one person at a time, so ErrPositionOccupied is returned
+//if a overlaps with another assignment to its position
+func (c *AssignmentCollection) AddAssignment(a *Assignment) error {
+
+	if c.byPerson == nil {
+		c.byPerson = make(map[*Person]*TimeTrackedEntityCollection[*Assignment])
+		c.byPosition = make(map[*Position]*TimeTrackedEntityCollection[*Assignment])
+	}
+
+	if held := c.byPosition[a.Position]; held != nil {
+		if len(held.FindIntersecting(a.from, a.until)) > 0 {
+			return ErrPositionOccupied
+		}
+	}
+
+	indexAssignment(c.byPerson, a.Person, a)
+	indexAssignment(c.byPosition, a.Position, a)
+
+	return nil
+}
+
+//RemoveAssignment removes a from the collection and returns
+//true, or returns false if a was not part of it
+func (c *AssignmentCollection) RemoveAssignment(a *Assignment) bool {
+
+	if c.byPerson[a.Person] == nil || !c.byPerson[a.Person].RemoveEntity(a) {
+		return false
+	}
+	c.byPosition[a.Position].RemoveEntity(a)
+
+	return true
+}
+
+//EndAssignment terminates the active assignment a at the
+//given pit, keeping both indexes up to date. The errors are
+//the ones of TimeTrackedEntityCollection.EndEntity
+func (c *AssignmentCollection) EndAssignment(a *Assignment, at time.Time) error {
+
+	if !a.until.IsZero() {
+		return ErrEntityAlreadyEnded
+	}
+	if !at.After(a.from) {
+		return ErrEndBeforeStart
+	}
+
+	// the assignment is moved in both trees, so it is
+	// taken out of both before its ending changes
+	if !c.RemoveAssignment(a) {
+		return ErrEntityNotFound
+	}
+	err := a.End(at)
+	indexAssignment(c.byPerson, a.Person, a)
+	indexAssignment(c.byPosition, a.Position, a)
+
+	return err
+}
+
+//EndPosition terminates position at the given pit. The
+//assignments to it held in the collection must have ended by
+//then, otherwise ErrOutsideParent is returned. Positions don't
+//know the collections they are assigned in, so ending them
+//directly, with End, doesn't check their assignments
+func (c *AssignmentCollection) EndPosition(position *Position, at time.Time) error {
+
+	if endsAfter(c.byPosition[position], at) {
+		return ErrOutsideParent
+	}
+
+	return position.End(at)
+}
+
+//EndPerson terminates person at the given pit. The
+//assignments of the person held in the collection must have
+//ended by then, otherwise ErrOutsideParent is returned, see
+//EndPosition
+func (c *AssignmentCollection) EndPerson(person *Person, at time.Time) error {
+
+	if endsAfter(c.byPerson[person], at) {
+		return ErrOutsideParent
+	}
+
+	return person.End(at)
+}
+
+//endsAfter checks if any of the assignments
+//of held is still in effect at or after at
+func endsAfter(held *TimeTrackedEntityCollection[*Assignment], at time.Time) bool {
+	return held != nil && len(held.FindIntersecting(at, NilTime())) > 0
+}
+
+//CurrentHolder returns the person holding position
+//at the given pit, or nil if it was vacant
+func (c *AssignmentCollection) CurrentHolder(position *Position, at time.Time) *Person {
+
+	if a := c.AssignmentAt(position, at); a != nil {
+		return a.Person
+	}
+
+	return nil
+}
+
+//AssignmentAt returns the assignment to position in
+//effect at the given pit, or nil if it was vacant
+func (c *AssignmentCollection) AssignmentAt(position *Position, at time.Time) *Assignment {
+
+	if held := c.byPosition[position]; held != nil {
+		if found := held.FindExistentAt(at); len(found) > 0 {
+			return found[0]
+		}
+	}
+
+	return nil
+}
+
+//PositionsHeldBy returns the positions person held at the
+//given pit, ordered by the start of their assignment
+func (c *AssignmentCollection) PositionsHeldBy(person *Person, at time.Time) []*Position {
+
+	held := c.byPerson[person]
+	if held == nil {
+		return nil
+	}
+
+	var positions []*Position
+	for _, a := range held.FindExistentAt(at) {
+		positions = append(positions, a.Position)
+	}
+
+	return positions
+}
+
+//AssignmentsOf returns the whole assignment history
+//of person, ordered by their start
+func (c *AssignmentCollection) AssignmentsOf(person *Person) []*Assignment {
+
+	if held := c.byPerson[person]; held != nil {
+		return held.Entities()
+	}
+
+	return nil
+}
+
+//AssignmentsTo returns the whole assignment history
+//of position, ordered by their start
+func (c *AssignmentCollection) AssignmentsTo(position *Position) []*Assignment {
+
+	if held := c.byPosition[position]; held != nil {
+		return held.Entities()
+	}
+
+	return nil
+}
+
+//indexAssignment adds a to the tree of key in index,
+//creating the tree if needed
+func indexAssignment[K comparable](index map[K]*TimeTrackedEntityCollection[*Assignment], key K, a *Assignment) {
+
+	if index[key] == nil {
+		index[key] = &TimeTrackedEntityCollection[*Assignment]{}
+	}
+	index[key].AddEntity(a)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignmentCollection(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	org, _ := NewOrganization("acme", "Acme", day(1), NilTime())
+	unit, _ := NewOrgUnit(org, nil, "sales", "Sales", day(1), NilTime())
+	head, _ := NewPosition(unit, "head", "Head of Sales", day(1), NilTime())
+	deputy, _ := NewPosition(unit, "deputy", "Deputy Head of Sales", day(1), NilTime())
+	ann, _ := NewPerson("ann", "Ann", day(1), NilTime())
+	bob, _ := NewPerson("bob", "Bob", day(5), NilTime())
+
+	if _, err := NewAssignment(bob, head, day(1), NilTime()); err != ErrOutsideParent {
+		t.Errorf("expected an assignment before the person joined to be rejected, got %v", err)
+	}
+
+	var assignments AssignmentCollection
+	annHead, _ := NewAssignment(ann, head, day(1), NilTime())
+	annDeputy, _ := NewAssignment(ann, deputy, day(3), NilTime())
+	for _, a := range []*Assignment{annHead, annDeputy} {
+		if err := assignments.AddAssignment(a); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	bobHead, _ := NewAssignment(bob, head, day(10), NilTime())
+	if err := assignments.AddAssignment(bobHead); err != ErrPositionOccupied {
+		t.Errorf("expected ErrPositionOccupied, got %v", err)
+	}
+	if err := assignments.EndAssignment(annHead, day(10)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := assignments.AddAssignment(bobHead); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if holder := assignments.CurrentHolder(head, day(9)); holder != ann {
+		t.Errorf("expected Ann to hold the position, got %v", holder)
+	}
+	if holder := assignments.CurrentHolder(head, day(10)); holder != bob {
+		t.Errorf("expected Bob to hold the position, got %v", holder)
+	}
+	if positions := assignments.PositionsHeldBy(ann, day(4)); len(positions) != 2 || positions[0] != head || positions[1] != deputy {
+		t.Errorf("unexpected positions %v", positions)
+	}
+	if positions := assignments.PositionsHeldBy(ann, day(12)); len(positions) != 1 || positions[0] != deputy {
+		t.Errorf("unexpected positions %v", positions)
+	}
+	if history := assignments.AssignmentsTo(head); len(history) != 2 || history[0] != annHead || history[1] != bobHead {
+		t.Errorf("unexpected history %v", history)
+	}
+
+	if !assignments.RemoveAssignment(annDeputy) || assignments.RemoveAssignment(annDeputy) {
+		t.Error("expected the assignment to be removed once")
+	}
+	if holder := assignments.CurrentHolder(deputy, day(12)); holder != nil {
+		t.Errorf("expected the position to be vacant, got %v", holder)
+	}
+	if history := assignments.AssignmentsOf(ann); len(history) != 1 || history[0] != annHead {
+		t.Errorf("unexpected history %v", history)
+	}
+}
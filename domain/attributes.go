@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//ErrAttributeNotFound is returned when reading an
+//attribute that has not been set
+var ErrAttributeNotFound = errors.New("attribute not found")
+
+//ErrAttributeType is returned when an attribute holds a
+//value of a different type than the one requested
+var ErrAttributeType = errors.New("attribute has a different type")
+
+//BaseAttributeBearer is a map backed implementation of
+//AttributeBearer, meant to be embedded in entities that
+//carry dynamic attributes. The zero value holds no
+//attributes and is ready to use
+type BaseAttributeBearer struct {
+	attributes map[string]interface{}
+	schema     *AttributeSchema
+}
+
+//GetAttributeNames returns the names of the attributes
+//sorted alphabetically
+func (b *BaseAttributeBearer) GetAttributeNames() []string {
+
+	names := make([]string, 0, len(b.attributes))
+	for name := range b.attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//HasAttribute checks if an attribute is present
+func (b *BaseAttributeBearer) HasAttribute(attrName string) bool {
+	_, ok := b.attributes[attrName]
+	return ok
+}
+
+//GetAttribute returns the value of the attribute, or
+//ErrAttributeNotFound if it has not been set
+func (b *BaseAttributeBearer) GetAttribute(attrName string) (interface{}, error) {
+
+	value, ok := b.attributes[attrName]
+	if !ok {
+		return nil, ErrAttributeNotFound
+	}
+
+	return value, nil
+}
+
+//SetAttribute sets the value of the attribute and returns
+//the previous one, or nil if it was not set
+func (b *BaseAttributeBearer) SetAttribute(attrName string, value interface{}) interface{} {
+
+	if b.attributes == nil {
+		b.attributes = make(map[string]interface{})
+	}
+
+	previous := b.attributes[attrName]
+	b.attributes[attrName] = value
+
+	return previous
+}
+
+//SetSchema makes SetAttributeChecked validate values against
+//schema, or stops validating them if schema is nil. The
+//attributes already set are not checked, Validate them
+//explicitly if needed. SetAttribute is never checked, since
+//the AttributeBearer interface has no way to report errors
+func (b *BaseAttributeBearer) SetSchema(schema *AttributeSchema) {
+	b.schema = schema
+}
+
+//SetAttributeChecked sets the value of the attribute like
+//SetAttribute, unless a schema is set and the value violates
+//it. In that case the attribute is left untouched and the
+//violation is returned
+func (b *BaseAttributeBearer) SetAttributeChecked(attrName string, value interface{}) (interface{}, error) {
+
+	if b.schema != nil {
+		if err := b.schema.ValidateAttribute(attrName, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.SetAttribute(attrName, value), nil
+}
+
+//GetAttributeAs returns the attribute of b as a T. The error
+//wraps ErrAttributeNotFound if the attribute is not set and
+//ErrAttributeType if it holds a value of another type
+func GetAttributeAs[T any](b AttributeBearer, attrName string) (T, error) {
+
+	var zero T
+	value, err := getAttribute(b, attrName)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("%s is %T, not %T: %w", attrName, value, zero, ErrAttributeType)
+	}
+
+	return typed, nil
+}
+
+//getAttribute returns the attribute of b, reporting a
+//missing attribute with ErrAttributeNotFound whatever
+//error the implementation of b returns for it
+func getAttribute(b AttributeBearer, attrName string) (interface{}, error) {
+
+	if !b.HasAttribute(attrName) {
+		return nil, fmt.Errorf("%s: %w", attrName, ErrAttributeNotFound)
+	}
+
+	return b.GetAttribute(attrName)
+}
+
+//GetString returns a string attribute of b,
+//see GetAttributeAs for the errors returned
+func GetString(b AttributeBearer, attrName string) (string, error) {
+	return GetAttributeAs[string](b, attrName)
+}
+
+//GetBool returns a boolean attribute of b,
+//see GetAttributeAs for the errors returned
+func GetBool(b AttributeBearer, attrName string) (bool, error) {
+	return GetAttributeAs[bool](b, attrName)
+}
+
+//GetTime returns a time attribute of b,
+//see GetAttributeAs for the errors returned
+func GetTime(b AttributeBearer, attrName string) (time.Time, error) {
+	return GetAttributeAs[time.Time](b, attrName)
+}
+
+//GetInt returns an integer attribute of b. Unlike
+//GetAttributeAs, values of any integer type are accepted
+//as long as they fit in an int
+func GetInt(b AttributeBearer, attrName string) (int, error) {
+
+	value, err := getAttribute(b, attrName)
+	if err != nil {
+		return 0, err
+	}
+
+	return toInt(attrName, value)
+}
+
+//toInt converts a value of any integer type to an int,
+//failing with ErrAttributeType if it is not an integer
+//or does not fit in an int
+func toInt(attrName string, value interface{}) (int, error) {
+
+	var converted int64
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int8:
+		converted = int64(v)
+	case int16:
+		converted = int64(v)
+	case int32:
+		converted = int64(v)
+	case int64:
+		converted = v
+	case uint8:
+		converted = int64(v)
+	case uint16:
+		converted = int64(v)
+	case uint32:
+		converted = int64(v)
+	case uint:
+		if uint64(v) > math.MaxInt {
+			return 0, fmt.Errorf("%s value %d overflows int: %w", attrName, v, ErrAttributeType)
+		}
+		return int(v), nil
+	case uint64:
+		if v > math.MaxInt {
+			return 0, fmt.Errorf("%s value %d overflows int: %w", attrName, v, ErrAttributeType)
+		}
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("%s is %T, not an integer: %w", attrName, value, ErrAttributeType)
+	}
+
+	if int64(int(converted)) != converted {
+		return 0, fmt.Errorf("%s value %d overflows int: %w", attrName, converted, ErrAttributeType)
+	}
+
+	return int(converted), nil
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockPerson struct {
+	mockTTEntity
+	BaseAttributeBearer
+}
+
+func TestBaseAttributeBearer(t *testing.T) {
+
+	p := &mockPerson{mockTTEntity: createMockTTEntity(time.Now(), NilTime()).(mockTTEntity)}
+	var _ AttributeBearer = p
+
+	if p.HasAttribute("grade") || len(p.GetAttributeNames()) != 0 {
+		t.Error("expected no attributes on the zero value")
+	}
+	if _, err := p.GetAttribute("grade"); err != ErrAttributeNotFound {
+		t.Errorf("expected ErrAttributeNotFound, got %v", err)
+	}
+
+	if previous := p.SetAttribute("grade", 7); previous != nil {
+		t.Errorf("expected no previous value, got %v", previous)
+	}
+	p.SetAttribute("name", "Ann")
+	if previous := p.SetAttribute("grade", int64(8)); previous != 7 {
+		t.Errorf("expected the previous value 7, got %v", previous)
+	}
+
+	names := p.GetAttributeNames()
+	if len(names) != 2 || names[0] != "grade" || names[1] != "name" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestTypedAttributeGetters(t *testing.T) {
+
+	hired := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	b := &BaseAttributeBearer{}
+	b.SetAttribute("name", "Ann")
+	b.SetAttribute("grade", int32(7))
+	b.SetAttribute("manager", true)
+	b.SetAttribute("hired", hired)
+	b.SetAttribute("huge", uint64(1<<63))
+
+	if v, err := GetString(b, "name"); err != nil || v != "Ann" {
+		t.Errorf("unexpected name %q, %v", v, err)
+	}
+	if v, err := GetInt(b, "grade"); err != nil || v != 7 {
+		t.Errorf("unexpected grade %d, %v", v, err)
+	}
+	if v, err := GetBool(b, "manager"); err != nil || !v {
+		t.Errorf("unexpected manager %v, %v", v, err)
+	}
+	if v, err := GetTime(b, "hired"); err != nil || !v.Equal(hired) {
+		t.Errorf("unexpected hired %v, %v", v, err)
+	}
+
+	if _, err := GetString(b, "missing"); !errors.Is(err, ErrAttributeNotFound) {
+		t.Errorf("expected ErrAttributeNotFound, got %v", err)
+	}
+	if _, err := GetString(b, "grade"); !errors.Is(err, ErrAttributeType) {
+		t.Errorf("expected ErrAttributeType, got %v", err)
+	}
+	if _, err := GetInt(b, "name"); !errors.Is(err, ErrAttributeType) {
+		t.Errorf("expected ErrAttributeType, got %v", err)
+	}
+	if _, err := GetInt(b, "huge"); !errors.Is(err, ErrAttributeType) {
+		t.Errorf("expected an overflow to be reported as ErrAttributeType, got %v", err)
+	}
+	if _, err := GetAttributeAs[int32](b, "grade"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
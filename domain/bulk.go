@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+//NewCollectionFromEntities builds a collection holding the
+//given entities. Instead of inserting them one by one, the
+//entities are sorted by their interval and linked directly
+//into a balanced tree, which takes O(n log n) for the sort,
+//skipped if they already are in order, and O(n) for the
+//tree. It is the way to load large histories, which inserted
+//in start order would degenerate the tree to a list. The
+//slice is not modified
+func NewCollectionFromEntities[T TimeTrackedEntity](entities []T) *TimeTrackedEntityCollection[T] {
+
+	// the intervals are read once, instead of
+	// on every comparison of the sort
+	type keyed struct {
+		from, until time.Time
+		node        *intervalNode[T]
+	}
+	keys := make([]keyed, len(entities))
+	for i, e := range entities {
+		until := e.ValidUntil()
+		keys[i] = keyed{from: e.ExistentFrom(), until: until, node: &intervalNode[T]{entity: e, max: until}}
+	}
+
+	compare := func(a, b keyed) int {
+		if c := a.from.Compare(b.from); c != 0 {
+			return c
+		}
+		return compareEndTime(a.until, b.until)
+	}
+	if !slices.IsSortedFunc(keys, compare) {
+		slices.SortStableFunc(keys, compare)
+	}
+
+	nodes := make([]*intervalNode[T], len(keys))
+	for i, k := range keys {
+		nodes[i] = k.node
+	}
+
+	return &TimeTrackedEntityCollection[T]{
+		root:      buildBalanced(nodes),
+		noOfNodes: len(nodes),
+		height:    optimalHeight(len(nodes)),
+	}
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+//randomEntities returns n entities with random intervals,
+//a tenth of them open ended
+func randomEntities(n int, rnd *rand.Rand) []TimeTrackedEntity {
+
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	entities := make([]TimeTrackedEntity, n)
+	for i := range entities {
+		from := start.Add(time.Duration(rnd.Intn(20*365*24)) * time.Hour)
+		end := NilTime()
+		if rnd.Intn(10) != 0 {
+			end = from.Add(time.Duration(rnd.Intn(5*365*24)+1) * time.Hour)
+		}
+		entities[i] = createMockTTEntity(from, end)
+	}
+
+	return entities
+}
+
+func TestNewCollectionFromEntities(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(1))
+	entities := randomEntities(1000, rnd)
+
+	bulk := NewCollectionFromEntities(entities)
+	if err := bulk.CheckInvariants(); err != nil {
+		t.Fatalf("invariants violated: %v", err)
+	}
+	if stats := bulk.Stats(); stats.Height != stats.OptimalHeight {
+		t.Errorf("expected an optimal tree, got %+v", stats)
+	}
+
+	inserted := EntityCollection{}
+	for _, e := range entities {
+		inserted.AddEntity(e)
+	}
+	if !Equal(bulk, &inserted) {
+		t.Error("expected the same entities as when inserting them one by one")
+	}
+	for i := 0; i < 50; i++ {
+		from := entities[rnd.Intn(len(entities))].ExistentFrom()
+		to := from.AddDate(0, rnd.Intn(24), 1)
+		if a, b := bulk.FindIntersecting(from, to), inserted.FindIntersecting(from, to); len(a) != len(b) {
+			t.Errorf("[%v, %v): expected %d entities, got %d", from, to, len(b), len(a))
+		}
+	}
+
+	// the bulk loaded collection keeps working as usual
+	bulk.AddEntity(createMockTTEntity(time.Now(), NilTime()))
+	if !bulk.RemoveEntity(entities[0]) {
+		t.Error("expected a bulk loaded entity to be removable")
+	}
+	if err := bulk.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+
+	if empty := NewCollectionFromEntities([]TimeTrackedEntity{}); len(empty.Entities()) != 0 || empty.CheckInvariants() != nil {
+		t.Error("expected an empty valid collection")
+	}
+}
+
+func BenchmarkNewCollectionFromEntities(b *testing.B) {
+	for _, n := range []int{1000, 200000} {
+		entities := randomEntities(n, rand.New(rand.NewSource(1)))
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				NewCollectionFromEntities(entities)
+			}
+		})
+	}
+}
+
+// entities are inserted in random order, in start
+// order the tree would degenerate to a list
+func BenchmarkRepeatedAddEntity(b *testing.B) {
+	for _, n := range []int{1000, 200000} {
+		entities := randomEntities(n, rand.New(rand.NewSource(1)))
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				collection := EntityCollection{}
+				for _, e := range entities {
+					collection.AddEntity(e)
+				}
+			}
+		})
+	}
+}
+
+// in start order every insertion walks the whole
+// tree, which has degenerated to a list
+func BenchmarkRepeatedAddEntitySorted(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		entities := skewedEntities(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				collection := EntityCollection{}
+				for _, e := range entities {
+					collection.AddEntity(e)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNewCollectionFromEntitiesSorted(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		entities := skewedEntities(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				NewCollectionFromEntities(entities)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+//EntityRecord is the plain data form of an entity, used to
+//persist and transfer entities without knowing their
+//concrete type
+type EntityRecord struct {
+	// identifies the entity among all the records
+	ID string
+	// tells codecs handling several entity types
+	// which one to decode the record to
+	Kind  string
+	From  time.Time
+	Until time.Time
+	// the attributes of the entity, if it is an AttributeBearer
+	Attributes map[string]interface{}
+	// the name or title of the entity, if it has one
+	Name string
+	// the IDs of the entities this one belongs to,
+	// keyed by their role, such as "parent"
+	Links map[string]string
+	// every value the attributes of the entity have had,
+	// if it is a TemporalAttributeBearer, see HistoryOf
+	History []AttributeValue
+}
+
+//EntityCodec converts entities of type T to records and back.
+//Since TimeTrackedEntity is an interface, storage backends and
+//serializers rely on a codec provided by the application to
+//rebuild the concrete entities
+type EntityCodec[T TimeTrackedEntity] interface {
+	Encode(e T) (EntityRecord, error)
+	Decode(r EntityRecord) (T, error)
+}
+
+//Attributes returns the attributes of e if it is
+//an AttributeBearer, or nil otherwise. It is meant
+//for codecs filling EntityRecord.Attributes
+func Attributes(e TimeTrackedEntity) map[string]interface{} {
+	return attributesOf(e)
+}
+
+//HistoryOf returns every value the attributes of b have had,
+//ordered by attribute name and then by the pit they were
+//set. It is meant for codecs filling EntityRecord.History,
+//which BaseTemporalAttributeBearer.RestoreHistory restores
+func HistoryOf(b TemporalAttributeBearer) []AttributeValue {
+
+	var history []AttributeValue
+	for _, name := range b.AttributeHistoryNames() {
+		for _, v := range b.AttributeHistory(name) {
+			history = append(history, *v)
+		}
+	}
+
+	return history
+}
+
+//identityKey returns the identity of the entities of the
+//collections of T: the kind and ID of the entities of the
+//organizational model, or else the ones the codec registered
+//for T encodes them with. Entities having neither are
+//identified by their concrete type alone
+func identityKey[T TimeTrackedEntity]() func(TimeTrackedEntity) string {
+
+	codec, err := codecFor[T]()
+
+	return func(e TimeTrackedEntity) string {
+		if kind, id, ok := modelIdentity(e); ok {
+			return kind + " " + id
+		}
+		if typed, ok := e.(T); ok && err == nil {
+			if record, err := codec.Encode(typed); err == nil {
+				return record.Kind + " " + record.ID
+			}
+		}
+		return fmt.Sprintf("%T", e)
+	}
+}
+
+//TypedCodec adapts a codec of TimeTrackedEntity, like
+//OrgCodec, to the collections of one of the types it
+//handles. Decoding a record of another type fails
+func TypedCodec[T TimeTrackedEntity](codec EntityCodec[TimeTrackedEntity]) EntityCodec[T] {
+	return typedCodec[T]{codec}
+}
+
+//typedCodec is the EntityCodec returned by TypedCodec
+type typedCodec[T TimeTrackedEntity] struct {
+	codec EntityCodec[TimeTrackedEntity]
+}
+
+func (c typedCodec[T]) Encode(e T) (EntityRecord, error) {
+	return c.codec.Encode(e)
+}
+
+func (c typedCodec[T]) Decode(r EntityRecord) (T, error) {
+
+	var zero T
+	e, err := c.codec.Decode(r)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := e.(T)
+	if !ok {
+		return zero, fmt.Errorf("%s is a %T, not a %T", r.ID, e, zero)
+	}
+
+	return typed, nil
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+//Mismatch is a single difference found when comparing
+//two entities or two collections
+type Mismatch struct {
+	// the key of the entity the mismatch refers to
+	Key string
+	// what differs: "presence", "ExistentFrom",
+	// "ValidUntil" or "attribute <name>"
+	Field string
+	// the values found on each side, nil
+	// if the side doesn't have one
+	A, B interface{}
+}
+
+//String implementation of a mismatch
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: %s differs (%v != %v)", m.Key, m.Field, m.A, m.B)
+}
+
+//Equal checks if two collections hold the same entities,
+//with the same intervals and attributes
+func Equal[T TimeTrackedEntity](a *TimeTrackedEntityCollection[T], b *TimeTrackedEntityCollection[T]) bool {
+	return len(Compare(a, b, nil)) == 0
+}
+
+//Compare matches the entities of a and b by the key returned
+//by key and reports every difference: entities present on one
+//side only and matched entities that differ, as reported by
+//CompareEntities. When key is nil the entities are matched by
+//identity: the entities of the organizational model by their
+//kind and ID, the rest by the kind and ID the codec registered
+//for T encodes them with, or by their concrete type alone if
+//there is no codec. Entities sharing a key are matched in the
+//order of their intervals, so pass a key to tell apart entities
+//without an ID
+func Compare[T TimeTrackedEntity](a *TimeTrackedEntityCollection[T], b *TimeTrackedEntityCollection[T], key func(TimeTrackedEntity) string) []Mismatch {
+
+	if key == nil {
+		key = identityKey[T]()
+	}
+
+	byKey := func(c *TimeTrackedEntityCollection[T]) map[string][]TimeTrackedEntity {
+		m := make(map[string][]TimeTrackedEntity)
+		for _, e := range c.Entities() {
+			k := key(e)
+			m[k] = append(m[k], e)
+		}
+		for _, entities := range m {
+			slices.SortStableFunc(entities, func(x, y TimeTrackedEntity) int {
+				if c := x.ExistentFrom().Compare(y.ExistentFrom()); c != 0 {
+					return c
+				}
+				return compareEndTime(x.ValidUntil(), y.ValidUntil())
+			})
+		}
+		return m
+	}
+	left, right := byKey(a), byKey(b)
+
+	keys := make([]string, 0, len(left)+len(right))
+	for k := range left {
+		keys = append(keys, k)
+	}
+	for k := range right {
+		if _, ok := left[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	mismatches := make([]Mismatch, 0)
+	for _, k := range keys {
+		l, r := left[k], right[k]
+		for i := 0; i < len(l) || i < len(r); i++ {
+			switch {
+			case i >= len(r):
+				mismatches = append(mismatches, Mismatch{Key: k, Field: "presence", A: l[i]})
+			case i >= len(l):
+				mismatches = append(mismatches, Mismatch{Key: k, Field: "presence", B: r[i]})
+			default:
+				for _, m := range CompareEntities(l[i], r[i]) {
+					m.Key = k
+					mismatches = append(mismatches, m)
+				}
+			}
+		}
+	}
+
+	return mismatches
+}
+
+//CompareEntities deeply compares two entities: their intervals
+//and, if they are AttributeBearers, their attributes. When both
+//are TemporalAttributeBearers the whole history of every
+//attribute is compared, not just the current values. The key
+//of the mismatches returned is the Format rendering of a
+func CompareEntities(a TimeTrackedEntity, b TimeTrackedEntity) []Mismatch {
+
+	k := Format(a)
+	mismatches := make([]Mismatch, 0)
+
+	if !a.ExistentFrom().Equal(b.ExistentFrom()) {
+		mismatches = append(mismatches, Mismatch{Key: k, Field: "ExistentFrom", A: a.ExistentFrom(), B: b.ExistentFrom()})
+	}
+	if !a.ValidUntil().Equal(b.ValidUntil()) {
+		mismatches = append(mismatches, Mismatch{Key: k, Field: "ValidUntil", A: a.ValidUntil(), B: b.ValidUntil()})
+	}
+
+	left, right := attributesOf(a), attributesOf(b)
+	if ta, ok := a.(TemporalAttributeBearer); ok {
+		if tb, ok := b.(TemporalAttributeBearer); ok {
+			left, right = historiesOf(ta), historiesOf(tb)
+		}
+	}
+	names := make([]string, 0, len(left)+len(right))
+	for name := range left {
+		names = append(names, name)
+	}
+	for name := range right {
+		if _, ok := left[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !reflect.DeepEqual(left[name], right[name]) {
+			mismatches = append(mismatches, Mismatch{Key: k, Field: "attribute " + name, A: left[name], B: right[name]})
+		}
+	}
+
+	return mismatches
+}
+
+//attributesOf returns the attributes of e if it
+//is an AttributeBearer, or nil otherwise
+func attributesOf(e TimeTrackedEntity) map[string]interface{} {
+
+	bearer, ok := e.(AttributeBearer)
+	if !ok {
+		return nil
+	}
+
+	attributes := make(map[string]interface{})
+	for _, name := range bearer.GetAttributeNames() {
+		if value, err := bearer.GetAttribute(name); err == nil {
+			attributes[name] = value
+		}
+	}
+
+	return attributes
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareEntities(t *testing.T) {
+
+	start := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	a := createMockAttributedEntity(start, NilTime(), map[string]interface{}{"grade": 3, "site": "Athens"})
+	b := createMockAttributedEntity(start, start.AddDate(0, 1, 0), map[string]interface{}{"grade": 4, "site": "Athens", "extra": true})
+
+	mismatches := CompareEntities(a, b)
+	if len(mismatches) != 3 {
+		t.Fatalf("expected 3 mismatches, got %v", mismatches)
+	}
+	if mismatches[0].Field != "ValidUntil" || mismatches[1].Field != "attribute extra" || mismatches[2].Field != "attribute grade" {
+		t.Errorf("unexpected mismatches %v", mismatches)
+	}
+	if mismatches[1].A != nil || mismatches[1].B != true || mismatches[2].A != 3 || mismatches[2].B != 4 {
+		t.Errorf("unexpected values %v", mismatches)
+	}
+
+	if m := CompareEntities(a, a); len(m) != 0 {
+		t.Errorf("expected no mismatches, got %v", m)
+	}
+}
+
+func TestCompareCollections(t *testing.T) {
+
+	start := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	shared := createMockTTEntity(start, NilTime())
+	onlyInA := createMockTTEntity(start, start.AddDate(0, 0, 3))
+
+	a := EntityCollection{}
+	a.AddEntity(shared)
+	a.AddEntity(onlyInA)
+
+	b := EntityCollection{}
+	b.AddEntity(shared)
+
+	// matched by the IDs the codec encodes
+	RegisterCodec[TimeTrackedEntity](mockCodec{})
+	t.Cleanup(UnregisterCodec[TimeTrackedEntity])
+
+	if Equal(&a, &b) {
+		t.Error("expected the collections to differ")
+	}
+
+	mismatches := Compare(&a, &b, nil)
+	if len(mismatches) != 1 || mismatches[0].Field != "presence" || mismatches[0].A != onlyInA || mismatches[0].B != nil {
+		t.Errorf("unexpected mismatches %v", mismatches)
+	}
+
+	b.AddEntity(onlyInA)
+	if !Equal(&a, &b) {
+		t.Errorf("expected equal collections, got %v", Compare(&a, &b, nil))
+	}
+
+	// matching by id reports the changed ending as a field mismatch
+	changed := onlyInA.(mockTTEntity)
+	changed.endAt = NilTime()
+	c := EntityCollection{}
+	c.AddEntity(shared)
+	c.AddEntity(changed)
+
+	byID := func(e TimeTrackedEntity) string { return e.(mockTTEntity).id }
+	mismatches = Compare(&a, &c, byID)
+	if len(mismatches) != 1 || mismatches[0].Field != "ValidUntil" || mismatches[0].Key != changed.id {
+		t.Errorf("unexpected mismatches %v", mismatches)
+	}
+}
+
+func TestCompareByIdentity(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// without a codec entities are matched by
+	// type, in the order of their intervals
+	first := createMockTTEntity(day(1), day(5))
+	second := createMockTTEntity(day(1), NilTime())
+	a := EntityCollection{}
+	a.AddEntity(second)
+	a.AddEntity(first)
+	b := EntityCollection{}
+	b.AddEntity(first)
+	b.AddEntity(second)
+	if mismatches := Compare(&a, &b, nil); len(mismatches) != 0 {
+		t.Errorf("expected equal collections, got %v", mismatches)
+	}
+
+	changed := first.(mockTTEntity)
+	changed.endAt = day(7)
+	c := EntityCollection{}
+	c.AddEntity(changed)
+	c.AddEntity(second)
+	mismatches := Compare(&a, &c, nil)
+	if len(mismatches) != 1 || mismatches[0].Field != "ValidUntil" || mismatches[0].Key != "domain.mockTTEntity" {
+		t.Errorf("unexpected mismatches %v", mismatches)
+	}
+
+	// the entities of the model are matched by kind and ID
+	_, entities := orgModel(t)
+	units := TimeTrackedEntityCollection[*OrgUnit]{}
+	ended := TimeTrackedEntityCollection[*OrgUnit]{}
+	for _, e := range entities {
+		if u, ok := e.(*OrgUnit); ok {
+			units.AddEntity(u)
+			if u.ID == "emea" {
+				copied := *u
+				copied.lifespan.until = day(25)
+				u = &copied
+			}
+			ended.AddEntity(u)
+		}
+	}
+	mismatches = Compare(&units, &ended, nil)
+	if len(mismatches) != 1 || mismatches[0].Field != "ValidUntil" || mismatches[0].Key != "unit emea" {
+		t.Errorf("unexpected mismatches %v", mismatches)
+	}
+}
@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+//ConcurrentTimeTrackedEntityCollection wraps a
+//TimeTrackedEntityCollection so it can be shared between
+//goroutines. Queries hold a read lock and may run in
+//parallel, while modifications hold the write lock and
+//are serialized. The zero value is an empty collection
+//ready to use
+type ConcurrentTimeTrackedEntityCollection[T TimeTrackedEntity] struct {
+	mu         sync.RWMutex
+	collection TimeTrackedEntityCollection[T]
+}
+
+//AddEntity adds a new entity to the collection,
+//see TimeTrackedEntityCollection.AddEntity
+func (c *ConcurrentTimeTrackedEntityCollection[T]) AddEntity(e T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.collection.AddEntity(e)
+}
+
+//RemoveEntity removes e from the collection,
+//see TimeTrackedEntityCollection.RemoveEntity
+func (c *ConcurrentTimeTrackedEntityCollection[T]) RemoveEntity(e T) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.collection.RemoveEntity(e)
+}
+
+//EndEntity terminates e at the given pit,
+//see TimeTrackedEntityCollection.EndEntity
+func (c *ConcurrentTimeTrackedEntityCollection[T]) EndEntity(e T, at time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.collection.EndEntity(e, at)
+}
+
+//AddEntities adds the entities in bulk while holding the
+//write lock, see TimeTrackedEntityCollection.AddEntities.
+//progress is called with the lock held
+func (c *ConcurrentTimeTrackedEntityCollection[T]) AddEntities(ctx context.Context, entities []T, progress ProgressFunc) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.collection.AddEntities(ctx, entities, progress)
+}
+
+//RebuildContext rebalances the underlying tree, see
+//TimeTrackedEntityCollection.RebuildContext. progress
+//is called with the lock held
+func (c *ConcurrentTimeTrackedEntityCollection[T]) RebuildContext(ctx context.Context, progress ProgressFunc) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.collection.RebuildContext(ctx, progress)
+}
+
+//Rebuild rebalances the underlying tree,
+//see TimeTrackedEntityCollection.Rebuild
+func (c *ConcurrentTimeTrackedEntityCollection[T]) Rebuild() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.collection.Rebuild()
+}
+
+//SetRebuildThreshold configures automatic rebuilds,
+//see TimeTrackedEntityCollection.SetRebuildThreshold
+func (c *ConcurrentTimeTrackedEntityCollection[T]) SetRebuildThreshold(threshold float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.collection.SetRebuildThreshold(threshold)
+}
+
+//Entities returns all the entities of the collection
+//ordered by their starting point
+func (c *ConcurrentTimeTrackedEntityCollection[T]) Entities() []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.Entities()
+}
+
+//FindIntersecting returns the entities existing at some
+//pit in [from, to), see TimeTrackedEntityCollection.FindIntersecting
+func (c *ConcurrentTimeTrackedEntityCollection[T]) FindIntersecting(from time.Time, to time.Time) []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.FindIntersecting(from, to)
+}
+
+//FindExistentAt returns the entities existing at pit
+func (c *ConcurrentTimeTrackedEntityCollection[T]) FindExistentAt(pit time.Time) []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.FindExistentAt(pit)
+}
+
+//WriteCanonical writes a deterministic rendering of the
+//collection to w, see TimeTrackedEntityCollection.WriteCanonical
+func (c *ConcurrentTimeTrackedEntityCollection[T]) WriteCanonical(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.WriteCanonical(w)
+}
+
+//Stats returns statistics about the shape of the
+//underlying interval tree
+func (c *ConcurrentTimeTrackedEntityCollection[T]) Stats() TreeStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.Stats()
+}
+
+//CheckInvariants verifies the underlying interval tree
+func (c *ConcurrentTimeTrackedEntityCollection[T]) CheckInvariants() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.CheckInvariants()
+}
+
+//View calls fn with the underlying collection while holding
+//the read lock, giving access to the queries that are not
+//wrapped. fn must not modify the collection nor keep a
+//reference to it after it returns
+func (c *ConcurrentTimeTrackedEntityCollection[T]) View(fn func(*TimeTrackedEntityCollection[T])) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	fn(&c.collection)
+}
+
+//Update calls fn with the underlying collection while
+//holding the write lock, so several modifications can be
+//applied without other goroutines observing the steps in
+//between. fn must not keep a reference to the collection
+//after it returns
+func (c *ConcurrentTimeTrackedEntityCollection[T]) Update(fn func(*TimeTrackedEntityCollection[T])) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn(&c.collection)
+}
+
+//MarshalJSON renders the collection under the read lock,
+//see TimeTrackedEntityCollection.MarshalJSON
+func (c *ConcurrentTimeTrackedEntityCollection[T]) MarshalJSON() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.collection.MarshalJSON()
+}
+
+//UnmarshalJSON replaces the entities under the write lock,
+//see TimeTrackedEntityCollection.UnmarshalJSON
+func (c *ConcurrentTimeTrackedEntityCollection[T]) UnmarshalJSON(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.collection.UnmarshalJSON(data)
+}
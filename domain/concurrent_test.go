@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// run with -race to detect unguarded accesses
+func TestConcurrentCollection(t *testing.T) {
+
+	var collection ConcurrentTimeTrackedEntityCollection[TimeTrackedEntity]
+	collection.SetRebuildThreshold(2)
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	const writers, perWriter = 8, 50
+
+	var wg sync.WaitGroup
+	endable := make(chan *mockEndableEntity, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				from := start.AddDate(0, 0, w*perWriter+i)
+				e := &mockEndableEntity{createMockTTEntity(from, NilTime()).(mockTTEntity)}
+				collection.AddEntity(e)
+				endable <- e
+			}
+		}(w)
+	}
+	for r := 0; r < writers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				collection.FindExistentAt(start.AddDate(0, 0, i*writers))
+				collection.FindIntersecting(start, start.AddDate(0, 1, 0))
+				collection.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+	close(endable)
+
+	// end half of the entities while querying
+	for e := range endable {
+		// read before ending, which writes the entity
+		from := e.ExistentFrom()
+		if from.YearDay()%2 == 0 {
+			continue
+		}
+		wg.Add(2)
+		go func(e *mockEndableEntity) {
+			defer wg.Done()
+			if err := collection.EndEntity(e, from.AddDate(0, 0, 1)); err != nil {
+				t.Errorf("cannot end %v: %v", e, err)
+			}
+		}(e)
+		go func(pit time.Time) {
+			defer wg.Done()
+			collection.View(func(c *TimeTrackedEntityCollection[TimeTrackedEntity]) {
+				c.ActiveDuring(NewPeriod(pit, pit.AddDate(0, 0, 7)))
+			})
+		}(from)
+	}
+	wg.Wait()
+
+	if n := len(collection.Entities()); n != writers*perWriter {
+		t.Errorf("expected %d entities, got %d", writers*perWriter, n)
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+//Package domaintest provides assertion helpers for testing
+//code that is built on top of the time tracking primitives
+//of package domain, so that downstream projects do not have
+//to reimplement the interval math in their own tests.
+package domaintest
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//AssertExistentAt fails the test if e does not exist at pit
+func AssertExistentAt(t testing.TB, e domain.TimeTrackedEntity, pit time.Time) {
+	t.Helper()
+
+	if !e.IsExistentAt(pit) {
+		t.Errorf("expected %v to be existent at %v", e, pit)
+	}
+}
+
+//AssertNotExistentAt fails the test if e exists at pit
+func AssertNotExistentAt(t testing.TB, e domain.TimeTrackedEntity, pit time.Time) {
+	t.Helper()
+
+	if e.IsExistentAt(pit) {
+		t.Errorf("expected %v not to be existent at %v", e, pit)
+	}
+}
+
+//AssertNoOverlaps fails the test if the intervals of any two
+//entities overlap. Only entities that are next to each other
+//when ordered by their starting point are reported, which is
+//enough to catch every overlap but may not list every pair.
+//Intervals are treated as closed at their start and open at
+//their end, so an entity ending at the exact pit another one
+//starts does not overlap with it
+func AssertNoOverlaps(t testing.TB, entities ...domain.TimeTrackedEntity) {
+	t.Helper()
+
+	sorted := sortedByStart(entities)
+	for i := 1; i < len(sorted); i++ {
+		previous := sorted[i-1]
+		if previous.ValidUntil().IsZero() || previous.ValidUntil().After(sorted[i].ExistentFrom()) {
+			t.Errorf("%v overlaps with %v", previous, sorted[i])
+		}
+	}
+}
+
+//AssertContiguous fails the test if the entities, ordered by
+//their starting point, leave gaps or overlap. That is, every
+//entity must end exactly when the next one starts and only
+//the last one may be open ended
+func AssertContiguous(t testing.TB, entities ...domain.TimeTrackedEntity) {
+	t.Helper()
+
+	sorted := sortedByStart(entities)
+	for i := 1; i < len(sorted); i++ {
+		previous := sorted[i-1]
+		switch {
+		case previous.ValidUntil().IsZero():
+			t.Errorf("%v is open ended but is followed by %v", previous, sorted[i])
+		case previous.ValidUntil().Before(sorted[i].ExistentFrom()):
+			t.Errorf("gap between %v and %v", previous, sorted[i])
+		case previous.ValidUntil().After(sorted[i].ExistentFrom()):
+			t.Errorf("%v overlaps with %v", previous, sorted[i])
+		}
+	}
+}
+
+//AssertTreeInvariants fails the test if the interval tree
+//behind the collection is no longer valid
+func AssertTreeInvariants[T domain.TimeTrackedEntity](t testing.TB, c *domain.TimeTrackedEntityCollection[T]) {
+	t.Helper()
+
+	if err := c.CheckInvariants(); err != nil {
+		t.Errorf("tree invariants violated: %v", err)
+	}
+}
+
+//sortedByStart returns a copy of entities ordered by
+//their starting point
+func sortedByStart(entities []domain.TimeTrackedEntity) []domain.TimeTrackedEntity {
+
+	sorted := make([]domain.TimeTrackedEntity, len(entities))
+	copy(sorted, entities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ExistentFrom().Before(sorted[j].ExistentFrom())
+	})
+
+	return sorted
+}
@@ -0,0 +1,128 @@
+package domaintest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+// ---- helper types and functions ----
+type mockTTEntity struct {
+	startFrom time.Time
+	endAt     time.Time
+}
+
+func (m mockTTEntity) IsExistentAt(pit time.Time) bool {
+
+	if m.startFrom.After(pit) {
+		return false
+	}
+
+	return m.endAt.IsZero() || m.endAt.After(pit)
+}
+
+func (m mockTTEntity) ExistentFrom() time.Time {
+	return m.startFrom
+}
+
+func (m mockTTEntity) ValidUntil() time.Time {
+	return m.endAt
+}
+
+func (m mockTTEntity) ActiveDuration() time.Duration {
+	return m.endAt.Sub(m.startFrom)
+}
+
+func (m mockTTEntity) String() string {
+	return fmt.Sprintf("[%s -- %s]", m.startFrom.Format("2006-01-02"), m.endAt.Format("2006-01-02"))
+}
+
+func day(d int) time.Time {
+	return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+//recorder captures failures instead of failing the real test
+type recorder struct {
+	testing.TB
+	failures int
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures++
+}
+
+// ------------------ Tests -------
+
+func TestAssertExistentAt(t *testing.T) {
+
+	e := mockTTEntity{startFrom: day(2), endAt: day(4)}
+
+	r := &recorder{TB: t}
+	AssertExistentAt(r, e, day(3))
+	AssertNotExistentAt(r, e, day(4))
+	if r.failures != 0 {
+		t.Errorf("expected no failures, got %d", r.failures)
+	}
+
+	AssertExistentAt(r, e, day(4))
+	AssertNotExistentAt(r, e, day(2))
+	if r.failures != 2 {
+		t.Errorf("expected 2 failures, got %d", r.failures)
+	}
+}
+
+func TestAssertNoOverlaps(t *testing.T) {
+
+	r := &recorder{TB: t}
+	AssertNoOverlaps(r,
+		mockTTEntity{startFrom: day(4), endAt: day(6)},
+		mockTTEntity{startFrom: day(1), endAt: day(4)},
+		mockTTEntity{startFrom: day(8)})
+	if r.failures != 0 {
+		t.Errorf("expected no failures, got %d", r.failures)
+	}
+
+	AssertNoOverlaps(r,
+		mockTTEntity{startFrom: day(1)},
+		mockTTEntity{startFrom: day(3), endAt: day(4)})
+	if r.failures != 1 {
+		t.Errorf("expected 1 failure, got %d", r.failures)
+	}
+}
+
+func TestAssertContiguous(t *testing.T) {
+
+	r := &recorder{TB: t}
+	AssertContiguous(r,
+		mockTTEntity{startFrom: day(4)},
+		mockTTEntity{startFrom: day(1), endAt: day(4)})
+	if r.failures != 0 {
+		t.Errorf("expected no failures, got %d", r.failures)
+	}
+
+	AssertContiguous(r,
+		mockTTEntity{startFrom: day(1), endAt: day(3)},
+		mockTTEntity{startFrom: day(4), endAt: day(6)},
+		mockTTEntity{startFrom: day(5)})
+	if r.failures != 2 {
+		t.Errorf("expected 2 failures, got %d", r.failures)
+	}
+}
+
+func TestAssertTreeInvariants(t *testing.T) {
+
+	collection := domain.EntityCollection{}
+	collection.AddEntity(mockTTEntity{startFrom: day(3), endAt: day(5)})
+	collection.AddEntity(mockTTEntity{startFrom: day(1)})
+	collection.AddEntity(mockTTEntity{startFrom: day(6), endAt: day(9)})
+
+	r := &recorder{TB: t}
+	AssertTreeInvariants(r, &collection)
+	if r.failures != 0 {
+		t.Errorf("expected no failures, got %d", r.failures)
+	}
+}
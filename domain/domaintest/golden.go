@@ -0,0 +1,102 @@
+package domaintest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//update makes the golden assertions rewrite the golden files
+//with the current output instead of comparing against them.
+//The flag is namespaced so it doesn't clash with flags of
+//the packages under test
+var update = flag.Bool("domaintest.update", false, "rewrite golden files with the current output")
+
+//AssertGolden compares got against the contents of the golden
+//file at path and fails the test with a line diff if they differ.
+//Running the tests with -domaintest.update (re)creates the file
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("cannot create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("cannot write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file (run with -domaintest.update to create it): %v", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Errorf("output differs from %s (-want +got):\n%s", path, lineDiff(string(want), string(got)))
+	}
+}
+
+//AssertCanonicalGolden renders the collection with
+//WriteCanonical and compares it against the golden file at path
+func AssertCanonicalGolden[T domain.TimeTrackedEntity](t testing.TB, path string, c *domain.TimeTrackedEntityCollection[T]) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := c.WriteCanonical(&buf); err != nil {
+		t.Fatalf("cannot render collection: %v", err)
+	}
+
+	AssertGolden(t, path, buf.Bytes())
+}
+
+//lineDiff returns a readable line by line diff between want
+//and got, based on their longest common subsequence. Removed
+//lines are prefixed with "-", added with "+" and common with " "
+func lineDiff(want, got string) string {
+
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// lcs[i][j] holds the length of the lcs of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var str strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			str.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			str.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			str.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return str.String()
+}
@@ -0,0 +1,40 @@
+package domaintest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+func TestAssertCanonicalGolden(t *testing.T) {
+
+	collection := domain.EntityCollection{}
+	collection.AddEntity(mockTTEntity{startFrom: day(3), endAt: day(5)})
+	collection.AddEntity(mockTTEntity{startFrom: day(1)})
+	collection.AddEntity(mockTTEntity{startFrom: day(6), endAt: day(9)})
+
+	AssertCanonicalGolden(t, filepath.Join("testdata", "collection.golden"), &collection)
+}
+
+func TestAssertGoldenReportsDiff(t *testing.T) {
+
+	if *update {
+		t.Skip("golden files are being updated")
+	}
+
+	r := &recorder{TB: t}
+	AssertGolden(r, filepath.Join("testdata", "collection.golden"), []byte("something else\n"))
+	if r.failures != 1 {
+		t.Errorf("expected 1 failure, got %d", r.failures)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+
+	diff := lineDiff("a\nb\nc", "a\nc\nd")
+	expected := "  a\n- b\n  c\n+ d\n"
+	if diff != expected {
+		t.Errorf("expected diff\n%s\ngot\n%s", expected, diff)
+	}
+}
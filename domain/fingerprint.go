@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Fingerprint returns a stable content hash (hex encoded
+//SHA-256) of the entities existent at pit. Two collections
+//holding the same entities at pit have the same fingerprint,
+//regardless of insertion order, tree shape or of entities
+//that are not existent at pit, so replicas and backups can
+//cheaply verify they hold identical state. Every entity is
+//hashed by its interval, its identity, as matched by Compare,
+//and the JSON form of the attributes it held at pit, see
+//AttributesAt. Unlike WriteCanonical, it doesn't depend on
+//the registered formatters
+func (ts *TimeTrackedEntityCollection[T]) Fingerprint(pit time.Time) string {
+
+	identity := identityKey[T]()
+	found := ts.FindExistentAt(pit)
+	lines := make([]string, 0, len(found))
+	for _, e := range found {
+		lines = append(lines, fingerprintLine(e, identity(e), AttributesAt(e, pit)))
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		io.WriteString(h, line+"\n")
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//fingerprintLine renders an entity the way
+//Fingerprint hashes it
+func fingerprintLine(e TimeTrackedEntity, identity string, attributes map[string]interface{}) string {
+
+	var str strings.Builder
+
+	str.WriteString(canonicalTime(e.ExistentFrom()))
+	str.WriteString("\t")
+	str.WriteString(canonicalTime(e.ValidUntil()))
+	str.WriteString("\t")
+	str.WriteString(identity)
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		str.WriteString("\t" + name + "=" + canonicalValue(attributes[name]))
+	}
+
+	return str.String()
+}
+
+//canonicalValue renders an attribute value in its JSON form,
+//with times in UTC, so that the same value is always rendered
+//the same way. Values JSON can't render fall back to %v
+func canonicalValue(value interface{}) string {
+
+	if t, ok := value.(time.Time); ok {
+		return canonicalTime(t)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+
+	first := createMockTTEntity(
+		time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC))
+	second := createMockTTEntity(
+		time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		NilTime())
+	third := createMockTTEntity(
+		time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		NilTime())
+	pit := time.Date(2020, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	a := EntityCollection{}
+	a.AddEntity(first)
+	a.AddEntity(second)
+
+	// different insertion order and an entity
+	// that doesn't exist at pit
+	b := EntityCollection{}
+	b.AddEntity(third)
+	b.AddEntity(second)
+	b.AddEntity(first)
+
+	if a.Fingerprint(pit) != b.Fingerprint(pit) {
+		t.Error("expected identical fingerprints at pit")
+	}
+	if a.Fingerprint(pit) != a.Fingerprint(pit) || len(a.Fingerprint(pit)) != 64 {
+		t.Errorf("unexpected fingerprint %q", a.Fingerprint(pit))
+	}
+
+	later := time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC)
+	if a.Fingerprint(later) == b.Fingerprint(later) {
+		t.Error("expected different fingerprints after the third entity started")
+	}
+}
+
+func TestFingerprintCanonical(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	labelled := func(label interface{}) TimeTrackedEntity {
+		return mockLabelledEntity{&mockEndableEntity{mockTTEntity{id: "a", startFrom: day(1)}}, label}
+	}
+
+	// equal entities held behind different pointers
+	a := EntityCollection{}
+	a.AddEntity(labelled("x"))
+	b := EntityCollection{}
+	b.AddEntity(labelled("x"))
+	if a.Fingerprint(day(2)) != b.Fingerprint(day(2)) {
+		t.Error("expected the fingerprint not to depend on pointers")
+	}
+
+	// the registered formatters don't matter
+	before := a.Fingerprint(day(2))
+	RegisterFormatter(labelled(nil), func(e TimeTrackedEntity) string { return "changed" })
+	t.Cleanup(func() { UnregisterFormatter(labelled(nil)) })
+	if a.Fingerprint(day(2)) != before {
+		t.Error("expected the fingerprint not to depend on the formatters")
+	}
+
+	// nor the location of time values
+	at := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	inUTC := EntityCollection{}
+	inUTC.AddEntity(createMockAttributedEntity(day(1), NilTime(), map[string]interface{}{"hired": at}))
+	inEET := EntityCollection{}
+	inEET.AddEntity(createMockAttributedEntity(day(1), NilTime(), map[string]interface{}{"hired": at.In(time.FixedZone("EET", 2*60*60))}))
+	if inUTC.Fingerprint(day(2)) != inEET.Fingerprint(day(2)) {
+		t.Error("expected the same time to be hashed the same way")
+	}
+
+	// the entities of the model are told apart by ID
+	ann, _ := NewPerson("ann", "Ann", day(1), NilTime())
+	bob, _ := NewPerson("bob", "Ann", day(1), NilTime())
+	persons := EntityCollection{}
+	persons.AddEntity(ann)
+	others := EntityCollection{}
+	others.AddEntity(bob)
+	if persons.Fingerprint(day(2)) == others.Fingerprint(day(2)) {
+		t.Error("expected different persons to have different fingerprints")
+	}
+}
@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//PeriodScheme defines how a fiscal year is divided
+//into its twelve periods
+type PeriodScheme int
+
+const (
+	// CalendarMonths uses calendar months as periods
+	CalendarMonths PeriodScheme = iota
+	// Weeks445 uses 4, 4 and 5 week periods per quarter
+	Weeks445
+	// Weeks454 uses 4, 5 and 4 week periods per quarter
+	Weeks454
+	// Weeks544 uses 5, 4 and 4 week periods per quarter
+	Weeks544
+)
+
+//weeksPerPeriod returns the weeks of the three
+//periods of a quarter for week based schemes
+func (s PeriodScheme) weeksPerPeriod() [3]int {
+	switch s {
+	case Weeks454:
+		return [3]int{4, 5, 4}
+	case Weeks544:
+		return [3]int{5, 4, 4}
+	}
+	return [3]int{4, 4, 5}
+}
+
+//FiscalCalendar holds the fiscal calendar settings of an
+//organization. Fiscal years are named after the calendar
+//year they end in. With a week based scheme a fiscal year
+//starts on the WeekStart day nearest to the first of
+//StartMonth, so it spans 52 or 53 weeks. The extra week
+//of a 53 week year is added to its last period
+type FiscalCalendar struct {
+	StartMonth time.Month
+	Scheme     PeriodScheme
+	WeekStart  time.Weekday
+	Location   *time.Location
+}
+
+//location returns the location of the calendar, UTC if not set
+func (c FiscalCalendar) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+//startMonth returns the first month of the fiscal year,
+//January if not set
+func (c FiscalCalendar) startMonth() time.Month {
+	if c.StartMonth == 0 {
+		return time.January
+	}
+	return c.StartMonth
+}
+
+//yearStart returns the first instant of fiscal year fy
+func (c FiscalCalendar) yearStart(fy int) time.Time {
+
+	startYear := fy
+	if c.startMonth() != time.January {
+		startYear--
+	}
+	nominal := time.Date(startYear, c.startMonth(), 1, 0, 0, 0, 0, c.location())
+
+	if c.Scheme == CalendarMonths {
+		return nominal
+	}
+
+	// move to the nearest WeekStart, at most 3 days away
+	diff := (int(c.WeekStart) - int(nominal.Weekday()) + 7) % 7
+	if diff > 3 {
+		diff -= 7
+	}
+	return nominal.AddDate(0, 0, diff)
+}
+
+//Year returns fiscal year fy
+func (c FiscalCalendar) Year(fy int) Period {
+	return Period{
+		start: c.yearStart(fy),
+		end:   c.yearStart(fy + 1),
+		name:  fmt.Sprintf("FY%d", fy),
+	}
+}
+
+//Quarter returns quarter q (1 to 4) of fiscal year fy
+func (c FiscalCalendar) Quarter(fy int, q int) Period {
+	return Period{
+		start: c.Period(fy, 3*q-2).start,
+		end:   c.Period(fy, 3*q).end,
+		name:  fmt.Sprintf("FY%dQ%d", fy, q),
+	}
+}
+
+//Period returns period n (1 to 12) of fiscal year fy
+func (c FiscalCalendar) Period(fy int, n int) Period {
+
+	name := fmt.Sprintf("FY%dP%02d", fy, n)
+	yearStart := c.yearStart(fy)
+
+	if c.Scheme == CalendarMonths {
+		return Period{
+			start: yearStart.AddDate(0, n-1, 0),
+			end:   yearStart.AddDate(0, n, 0),
+			name:  name,
+		}
+	}
+
+	weeks := c.Scheme.weeksPerPeriod()
+	weeksBefore := 0
+	for i := 1; i < n; i++ {
+		weeksBefore += weeks[(i-1)%3]
+	}
+
+	start := yearStart.AddDate(0, 0, 7*weeksBefore)
+	end := start.AddDate(0, 0, 7*weeks[(n-1)%3])
+	if n == 12 {
+		end = c.yearStart(fy + 1)
+	}
+
+	return Period{start: start, end: end, name: name}
+}
+
+//YearOf returns the fiscal year pit falls in
+func (c FiscalCalendar) YearOf(pit time.Time) int {
+
+	fy := pit.Year()
+	for c.Year(fy).End().After(pit) && !c.Year(fy).Contains(pit) {
+		fy--
+	}
+	for !c.Year(fy).Contains(pit) {
+		fy++
+	}
+
+	return fy
+}
+
+//QuarterOf returns the fiscal quarter pit falls in
+func (c FiscalCalendar) QuarterOf(pit time.Time) Period {
+
+	fy := c.YearOf(pit)
+	for q := 1; q < 4; q++ {
+		if c.Quarter(fy, q).Contains(pit) {
+			return c.Quarter(fy, q)
+		}
+	}
+
+	return c.Quarter(fy, 4)
+}
+
+//PeriodOf returns the fiscal period pit falls in
+func (c FiscalCalendar) PeriodOf(pit time.Time) Period {
+
+	fy := c.YearOf(pit)
+	for n := 1; n < 12; n++ {
+		if c.Period(fy, n).Contains(pit) {
+			return c.Period(fy, n)
+		}
+	}
+
+	return c.Period(fy, 12)
+}
+
+//ParseAsOf resolves an as-of expression like the package level
+//ParseAsOf, in the location of the calendar, and additionally
+//understands boundaries of fiscal units, as in "end of last
+//fiscal quarter", "start of this fiscal year" or "end of
+//previous fiscal period"
+func (c FiscalCalendar) ParseAsOf(expr string, now time.Time) (time.Time, error) {
+
+	now = now.In(c.location())
+	normalized := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+
+	for prefix, end := range map[string]bool{"start of ": false, "beginning of ": false, "end of ": true} {
+		if !strings.HasPrefix(normalized, prefix) {
+			continue
+		}
+
+		parts := strings.Split(strings.TrimPrefix(normalized, prefix), " ")
+		if len(parts) != 3 || parts[1] != "fiscal" {
+			break
+		}
+
+		offsets := map[string]int{"this": 0, "current": 0, "last": -1, "previous": -1, "next": 1}
+		offset, ok := offsets[parts[0]]
+		if !ok {
+			return NilTime(), fmt.Errorf("unrecognized date expression %q", expr)
+		}
+
+		var p Period
+		switch parts[2] {
+		case "year":
+			p = c.Year(c.YearOf(now) + offset)
+		case "quarter":
+			p = c.shift(c.QuarterOf(now), offset, c.QuarterOf)
+		case "period":
+			p = c.shift(c.PeriodOf(now), offset, c.PeriodOf)
+		default:
+			return NilTime(), fmt.Errorf("invalid unit in date expression %q", expr)
+		}
+
+		if end {
+			return p.End().Add(-time.Nanosecond), nil
+		}
+		return p.Start(), nil
+	}
+
+	return ParseAsOf(expr, now)
+}
+
+//shift moves offset periods away from p, using periodOf
+//to find the period adjacent to a boundary
+func (c FiscalCalendar) shift(p Period, offset int, periodOf func(time.Time) Period) Period {
+
+	for ; offset > 0; offset-- {
+		p = periodOf(p.End())
+	}
+	for ; offset < 0; offset++ {
+		p = periodOf(p.Start().Add(-time.Nanosecond))
+	}
+
+	return p
+}
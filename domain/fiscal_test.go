@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiscalCalendarMonths(t *testing.T) {
+
+	calendar := FiscalCalendar{StartMonth: time.July}
+
+	fy := calendar.Year(2021)
+	if fy.Start() != time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC) || fy.End() != time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected fiscal year %s [%v, %v)", fy, fy.Start(), fy.End())
+	}
+
+	q3 := calendar.Quarter(2021, 3)
+	if q3.Start() != time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) || q3.String() != "FY2021Q3" {
+		t.Errorf("unexpected quarter %s starting %v", q3, q3.Start())
+	}
+
+	pit := time.Date(2021, 8, 15, 0, 0, 0, 0, time.UTC)
+	if calendar.YearOf(pit) != 2022 {
+		t.Errorf("expected FY2022, got FY%d", calendar.YearOf(pit))
+	}
+	if p := calendar.PeriodOf(pit); p.String() != "FY2022P02" {
+		t.Errorf("expected FY2022P02, got %s", p)
+	}
+}
+
+func TestFiscalCalendarWeeks(t *testing.T) {
+
+	calendar := FiscalCalendar{StartMonth: time.January, Scheme: Weeks445, WeekStart: time.Sunday}
+
+	// Jan 1st 2023 is a Sunday, 2022 starts on Jan 2nd 2022
+	fy := calendar.Year(2022)
+	if fy.Start() != time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC) || fy.End() != time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected fiscal year [%v, %v)", fy.Start(), fy.End())
+	}
+
+	weeks := []int{4, 4, 5, 4, 4, 5, 4, 4, 5, 4, 4, 5}
+	for n := 1; n <= 12; n++ {
+		p := calendar.Period(2022, n)
+		if days := int(p.End().Sub(p.Start()).Hours() / 24); days != 7*weeks[n-1] {
+			t.Errorf("period %d: expected %d weeks, got %d days", n, weeks[n-1], days)
+		}
+	}
+
+	// a 53 week year adds the extra week to its last period
+	long := calendar.Year(2025)
+	if days := int(long.End().Sub(long.Start()).Hours() / 24); days != 371 {
+		t.Errorf("expected a 53 week year, got %d days", days)
+	}
+	if p := calendar.Period(2025, 12); int(p.End().Sub(p.Start()).Hours()/24) != 42 {
+		t.Errorf("expected 6 weeks in the last period, got [%v, %v)", p.Start(), p.End())
+	}
+	if calendar.Quarter(2022, 1).End() != calendar.Period(2022, 4).Start() {
+		t.Error("quarters and periods are not aligned")
+	}
+}
+
+func TestFiscalCalendarParseAsOf(t *testing.T) {
+
+	calendar := FiscalCalendar{StartMonth: time.October}
+	now := time.Date(2021, 11, 20, 10, 0, 0, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"end of last fiscal quarter":  time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond),
+		"start of this fiscal year":   time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+		"start of next fiscal period": time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+		"end of last quarter":         time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond),
+		"2021-06":                     time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for expr, expected := range cases {
+		got, err := calendar.ParseAsOf(expr, now)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", expr, err)
+			continue
+		}
+		if !got.Equal(expected) {
+			t.Errorf("%q: expected %v, got %v", expr, expected, got)
+		}
+	}
+
+	if _, err := calendar.ParseAsOf("end of last fiscal decade", now); err == nil {
+		t.Error("expected an error for an unknown fiscal unit")
+	}
+}
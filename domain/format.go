@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//Formatter renders an entity as text
+type Formatter func(e TimeTrackedEntity) string
+
+//formatters holds the registered formatters
+//keyed by the concrete type they render
+var formatters = struct {
+	sync.RWMutex
+	byType map[reflect.Type]Formatter
+}{byType: make(map[reflect.Type]Formatter)}
+
+//RegisterFormatter installs f as the formatter for entities
+//of the same concrete type as example. The formatter is used
+//wherever the package renders entities: the String of the
+//collection, canonical exports and debug dumps. Registering
+//again for the same type replaces the previous formatter
+func RegisterFormatter(example TimeTrackedEntity, f Formatter) {
+
+	formatters.Lock()
+	defer formatters.Unlock()
+
+	formatters.byType[reflect.TypeOf(example)] = f
+}
+
+//UnregisterFormatter removes the formatter installed for
+//the concrete type of example, if any
+func UnregisterFormatter(example TimeTrackedEntity) {
+
+	formatters.Lock()
+	defer formatters.Unlock()
+
+	delete(formatters.byType, reflect.TypeOf(example))
+}
+
+//Format renders e with the formatter registered for its
+//concrete type, or with %v if there is none
+func Format(e TimeTrackedEntity) string {
+
+	formatters.RLock()
+	f, ok := formatters.byType[reflect.TypeOf(e)]
+	formatters.RUnlock()
+
+	if ok {
+		return f(e)
+	}
+
+	return fmt.Sprintf("%v", e)
+}
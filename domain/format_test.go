@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatterRegistry(t *testing.T) {
+
+	e := createMockTTEntity(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), NilTime())
+	if Format(e) != e.(mockTTEntity).String() {
+		t.Errorf("expected the %%v rendering, got %q", Format(e))
+	}
+
+	RegisterFormatter(mockTTEntity{}, func(e TimeTrackedEntity) string {
+		return "mock from " + e.ExistentFrom().Format("2006-01-02")
+	})
+	defer UnregisterFormatter(mockTTEntity{})
+
+	if Format(e) != "mock from 2020-01-02" {
+		t.Errorf("expected the registered rendering, got %q", Format(e))
+	}
+
+	collection := EntityCollection{}
+	collection.AddEntity(e)
+	if !strings.Contains(collection.String(), "[E:mock from 2020-01-02 ") {
+		t.Errorf("collection doesn't use the formatter: %s", collection)
+	}
+
+	var str strings.Builder
+	collection.WriteCanonical(&str)
+	if str.String() != "2020-01-02T00:00:00Z\t-\tmock from 2020-01-02\n" {
+		t.Errorf("canonical rendering doesn't use the formatter: %q", str.String())
+	}
+
+	// other types are not affected
+	attributed := createMockAttributedEntity(e.ExistentFrom(), NilTime(), map[string]interface{}{})
+	if strings.HasPrefix(Format(attributed), "mock from") {
+		t.Errorf("formatter applied to another type: %q", Format(attributed))
+	}
+}
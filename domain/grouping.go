@@ -0,0 +1,247 @@
+package domain
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Aggregation reduces the entities of a group to a single
+//value, reading their attributes as they were at asOf
+type Aggregation func(entities []TimeTrackedEntity, asOf time.Time) float64
+
+//Count is an Aggregation returning the number of entities
+func Count() Aggregation {
+	return func(entities []TimeTrackedEntity, asOf time.Time) float64 {
+		return float64(len(entities))
+	}
+}
+
+//SumOf is an Aggregation summing the numeric attribute
+//attrName over the entities. Entities that don't carry the
+//attribute, or carry a non numeric value, are ignored
+func SumOf(attrName string) Aggregation {
+	return func(entities []TimeTrackedEntity, asOf time.Time) float64 {
+		sum, _ := sumAttribute(entities, attrName, asOf)
+		return sum
+	}
+}
+
+//AverageOf is an Aggregation averaging the numeric attribute
+//attrName over the entities that carry it
+func AverageOf(attrName string) Aggregation {
+	return func(entities []TimeTrackedEntity, asOf time.Time) float64 {
+		sum, count := sumAttribute(entities, attrName, asOf)
+		if count == 0 {
+			return 0
+		}
+		return sum / float64(count)
+	}
+}
+
+//Group is a single cell of a pivot summary produced by
+//GroupBy. Nested groups break the cell further down by the
+//next attribute requested
+type Group struct {
+	// the attribute the entities were grouped by
+	Attribute string
+	// the value all the entities of the group share,
+	// nil for entities that don't carry the attribute
+	Value interface{}
+	// the number of entities in the group
+	Size int
+	// the outcome of the aggregation over the group
+	Result float64
+	// the groups below this one, if more
+	// attributes were requested
+	Subgroups []*Group
+}
+
+//GroupBy groups the entities existent at asOf by the values of
+//the given attributes, one level per attribute, and applies agg
+//on every group. Attributes are read as they were at asOf, see
+//AttributeAt. Entities that are not AttributeBearers are left
+//out. Groups of every level are ordered by their value, see
+//compareGroupValues
+func (ts *TimeTrackedEntityCollection[T]) GroupBy(attributes []string, agg Aggregation, asOf time.Time) []*Group {
+
+	entities := make([]TimeTrackedEntity, 0)
+	for _, e := range ts.FindExistentAt(asOf) {
+		if _, ok := TimeTrackedEntity(e).(AttributeBearer); ok {
+			entities = append(entities, e)
+		}
+	}
+
+	return groupEntities(entities, attributes, agg, asOf)
+}
+
+//groupEntities splits entities by the first attribute
+//and recurses for the rest
+func groupEntities(entities []TimeTrackedEntity, attributes []string, agg Aggregation, asOf time.Time) []*Group {
+
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	attrName := attributes[0]
+	byKey := make(map[string]*Group)
+	members := make(map[string][]TimeTrackedEntity)
+	for _, e := range entities {
+		value, err := AttributeAt(e.(AttributeBearer), attrName, asOf)
+		if err != nil {
+			// the entity doesn't carry the attribute
+			value = nil
+		}
+
+		key := groupKey(value)
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = &Group{Attribute: attrName, Value: value}
+		}
+		members[key] = append(members[key], e)
+	}
+
+	groups := make([]*Group, 0, len(byKey))
+	for key, g := range byKey {
+		g.Size = len(members[key])
+		g.Result = agg(members[key], asOf)
+		g.Subgroups = groupEntities(members[key], attributes[1:], agg, asOf)
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return compareGroupValues(groups[i].Value, groups[j].Value) < 0
+	})
+
+	return groups
+}
+
+//groupKey identifies the group of value. The type is part
+//of the key, so 1 and "1" fall in different groups
+func groupKey(value interface{}) string {
+	return fmt.Sprintf("%T %#v", value, value)
+}
+
+//compareGroupValues orders the values of groups: numbers by
+//their value, then strings, booleans and times in their
+//natural order, then any other value by its rendering, and
+//nil, standing for the missing attribute, last. It returns
+//a negative number if a comes first, a positive one if b
+//does and zero if they are the same
+func compareGroupValues(a interface{}, b interface{}) int {
+
+	if c := cmp.Compare(valueClass(a), valueClass(b)); c != 0 {
+		return c
+	}
+
+	var c int
+	switch x := a.(type) {
+	case string:
+		c = strings.Compare(x, b.(string))
+	case bool:
+		c = cmp.Compare(boolRank(x), boolRank(b.(bool)))
+	case time.Time:
+		c = x.Compare(b.(time.Time))
+	default:
+		if f, ok := toFloat(a); ok {
+			g, _ := toFloat(b)
+			c = cmp.Compare(f, g)
+		} else {
+			c = strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+		}
+	}
+	if c != 0 {
+		return c
+	}
+
+	// equal values of different types, like 1 and 1.0
+	return strings.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b))
+}
+
+//valueClass ranks the kinds of values a group may have
+func valueClass(value interface{}) int {
+
+	if _, ok := toFloat(value); ok {
+		return 0
+	}
+	switch value.(type) {
+	case string:
+		return 1
+	case bool:
+		return 2
+	case time.Time:
+		return 3
+	case nil:
+		return 5
+	}
+
+	return 4
+}
+
+//boolRank orders false before true
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+//sumAttribute sums the numeric values of attrName at asOf and
+//returns the sum along with how many values were summed
+func sumAttribute(entities []TimeTrackedEntity, attrName string, asOf time.Time) (float64, int) {
+
+	var sum float64
+	var count int
+	for _, e := range entities {
+		bearer, ok := e.(AttributeBearer)
+		if !ok {
+			continue
+		}
+
+		value, err := AttributeAt(bearer, attrName, asOf)
+		if err != nil {
+			continue
+		}
+
+		if f, ok := toFloat(value); ok {
+			sum += f
+			count++
+		}
+	}
+
+	return sum, count
+}
+
+//toFloat converts the numeric kinds an attribute
+//may hold to a float64
+func toFloat(value interface{}) (float64, bool) {
+
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
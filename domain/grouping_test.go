@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// ---- helper types and functions ----
+type mockAttributedEntity struct {
+	mockTTEntity
+	attributes map[string]interface{}
+}
+
+func (m mockAttributedEntity) GetAttributeNames() []string {
+	names := make([]string, 0, len(m.attributes))
+	for name := range m.attributes {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (m mockAttributedEntity) HasAttribute(attrName string) bool {
+	_, ok := m.attributes[attrName]
+	return ok
+}
+
+func (m mockAttributedEntity) GetAttribute(attrName string) (interface{}, error) {
+	value, ok := m.attributes[attrName]
+	if !ok {
+		return nil, errors.New("no such attribute")
+	}
+	return value, nil
+}
+
+func (m mockAttributedEntity) SetAttribute(attrName string, value interface{}) interface{} {
+	previous := m.attributes[attrName]
+	m.attributes[attrName] = value
+	return previous
+}
+
+func createMockAttributedEntity(start time.Time, end time.Time, attributes map[string]interface{}) TimeTrackedEntity {
+	return mockAttributedEntity{
+		mockTTEntity: createMockTTEntity(start, end).(mockTTEntity),
+		attributes:   attributes,
+	}
+}
+
+// ------------------ Tests -------
+
+func TestGroupBy(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	collection := EntityCollection{}
+	collection.AddEntity(createMockAttributedEntity(start, NilTime(),
+		map[string]interface{}{"location": "Athens", "type": "full", "salary": 10}))
+	collection.AddEntity(createMockAttributedEntity(start, NilTime(),
+		map[string]interface{}{"location": "Athens", "type": "part", "salary": 4}))
+	collection.AddEntity(createMockAttributedEntity(start, NilTime(),
+		map[string]interface{}{"location": "Athens", "type": "full", "salary": 12}))
+	collection.AddEntity(createMockAttributedEntity(start, NilTime(),
+		map[string]interface{}{"location": "Patras", "type": "full", "salary": 8.5}))
+	// not existent at asOf
+	collection.AddEntity(createMockAttributedEntity(start, start.AddDate(0, 1, 0),
+		map[string]interface{}{"location": "Patras", "type": "full", "salary": 8}))
+	// not an attribute bearer
+	collection.AddEntity(createMockTTEntity(start, NilTime()))
+
+	groups := collection.GroupBy([]string{"location", "type"}, Count(), asOf)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	athens := groups[0]
+	if athens.Value != "Athens" || athens.Size != 3 || athens.Result != 3 {
+		t.Errorf("unexpected group %+v", athens)
+	}
+	if len(athens.Subgroups) != 2 || athens.Subgroups[0].Value != "full" || athens.Subgroups[0].Result != 2 {
+		t.Errorf("unexpected subgroups %+v", athens.Subgroups)
+	}
+	if groups[1].Value != "Patras" || groups[1].Size != 1 {
+		t.Errorf("unexpected group %+v", groups[1])
+	}
+
+	groups = collection.GroupBy([]string{"location"}, AverageOf("salary"), asOf)
+	if groups[0].Result != 26.0/3 || groups[1].Result != 8.5 {
+		t.Errorf("unexpected averages %v, %v", groups[0].Result, groups[1].Result)
+	}
+
+	groups = collection.GroupBy([]string{"missing"}, SumOf("salary"), asOf)
+	if len(groups) != 1 || groups[0].Value != nil || groups[0].Result != 34.5 {
+		t.Errorf("unexpected groups for a missing attribute %+v", groups)
+	}
+}
+
+func TestGroupByValueOrder(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	collection := EntityCollection{}
+	for _, grade := range []interface{}{10, "1", 9, 1, "10", 2.5, nil, "9", 1} {
+		attributes := map[string]interface{}{}
+		if grade != nil {
+			attributes["grade"] = grade
+		}
+		collection.AddEntity(createMockAttributedEntity(start, NilTime(), attributes))
+	}
+
+	groups := collection.GroupBy([]string{"grade"}, Count(), asOf)
+	// numbers in their natural order, then strings, and
+	// the entities missing the attribute last
+	expected := []interface{}{1, 2.5, 9, 10, "1", "10", "9", nil}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d groups, got %d", len(expected), len(groups))
+	}
+	for i, value := range expected {
+		if groups[i].Value != value {
+			t.Errorf("expected %v (%T) at %d, got %v (%T)", value, value, i, groups[i].Value, groups[i].Value)
+		}
+	}
+	if groups[0].Size != 2 || groups[4].Size != 1 {
+		t.Errorf("expected 1 and \"1\" in different groups, got %+v and %+v", groups[0], groups[4])
+	}
+
+	dates := EntityCollection{}
+	for _, d := range []int{20, 3, 11} {
+		dates.AddEntity(createMockAttributedEntity(start, NilTime(),
+			map[string]interface{}{"hired": time.Date(2019, 1, d, 0, 0, 0, 0, time.UTC)}))
+	}
+	groups = dates.GroupBy([]string{"hired"}, Count(), asOf)
+	for i := 1; i < len(groups); i++ {
+		if !groups[i-1].Value.(time.Time).Before(groups[i].Value.(time.Time)) {
+			t.Errorf("expected times in chronological order, got %v", groups)
+		}
+	}
+}
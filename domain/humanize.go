@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Locale holds the words used when humanizing durations
+type Locale struct {
+	Year, Years               string
+	Month, Months             string
+	Day, Days                 string
+	BusinessDay, BusinessDays string
+	LessThanADay              string
+	Separator                 string
+}
+
+//English is the default locale for humanized durations
+var English = Locale{
+	Year: "year", Years: "years",
+	Month: "month", Months: "months",
+	Day: "day", Days: "days",
+	BusinessDay: "business day", BusinessDays: "business days",
+	LessThanADay: "less than a day",
+	Separator:    ", ",
+}
+
+//Greek locale for humanized durations
+var Greek = Locale{
+	Year: "έτος", Years: "έτη",
+	Month: "μήνας", Months: "μήνες",
+	Day: "ημέρα", Days: "ημέρες",
+	BusinessDay: "εργάσιμη ημέρα", BusinessDays: "εργάσιμες ημέρες",
+	LessThanADay: "λιγότερο από μία ημέρα",
+	Separator:    ", ",
+}
+
+//HumanizeOptions configures how durations are humanized.
+//The zero value formats in English with up to two units
+type HumanizeOptions struct {
+	// the words to use, English when not set
+	Locale *Locale
+	// the maximum number of units shown, 2 when
+	// not set, as in "3 years, 4 months"
+	MaxUnits int
+	// count the weekdays in between, as in
+	// "42 business days", instead of calendar units
+	BusinessDays bool
+}
+
+//FormatTenure humanizes the span from..to in calendar units,
+//like "3 years, 4 months". Units are truncated, never rounded
+func FormatTenure(from time.Time, to time.Time, opts HumanizeOptions) string {
+
+	locale := opts.Locale
+	if locale == nil {
+		locale = &English
+	}
+	maxUnits := opts.MaxUnits
+	if maxUnits <= 0 {
+		maxUnits = 2
+	}
+
+	if to.Before(from) {
+		from, to = to, from
+	}
+
+	if opts.BusinessDays {
+		n := businessDays(from, to)
+		if n == 0 {
+			return locale.LessThanADay
+		}
+		return plural(n, locale.BusinessDay, locale.BusinessDays)
+	}
+
+	years, months, days := calendarDiff(from, to)
+
+	var parts []string
+	for _, unit := range []struct {
+		n              int
+		singular, many string
+	}{
+		{years, locale.Year, locale.Years},
+		{months, locale.Month, locale.Months},
+		{days, locale.Day, locale.Days},
+	} {
+		if unit.n > 0 && len(parts) < maxUnits {
+			parts = append(parts, plural(unit.n, unit.singular, unit.many))
+		}
+	}
+
+	if len(parts) == 0 {
+		return locale.LessThanADay
+	}
+
+	return strings.Join(parts, locale.Separator)
+}
+
+//HumanizeActiveDuration humanizes how long the entity has been
+//existent, up to its ending or up to now if it is still active
+func HumanizeActiveDuration(e TimeTrackedEntity, now time.Time, opts HumanizeOptions) string {
+
+	end := e.ValidUntil()
+	if end.IsZero() {
+		end = now
+	}
+
+	return FormatTenure(e.ExistentFrom(), end, opts)
+}
+
+//HumanizeDuration humanizes a raw duration. As a duration is
+//not anchored in the calendar, it is laid out from the Unix
+//epoch, so months and years are approximate
+func HumanizeDuration(d time.Duration, opts HumanizeOptions) string {
+	from := time.Unix(0, 0).UTC()
+	return FormatTenure(from, from.Add(d), opts)
+}
+
+//calendarDiff returns the whole years, months and days
+//between from and to, where from is not after to. Adding
+//months to the end of a month clamps to the end of the
+//target month, so Jan 31st plus one month is Feb 28th
+func calendarDiff(from time.Time, to time.Time) (int, int, int) {
+
+	to = to.In(from.Location())
+
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	anchor := addMonthsClamped(from, months)
+	for months > 0 && anchor.After(to) {
+		months--
+		anchor = addMonthsClamped(from, months)
+	}
+
+	days := 0
+	for !anchor.AddDate(0, 0, days+1).After(to) {
+		days++
+	}
+
+	return months / 12, months % 12, days
+}
+
+//addMonthsClamped adds months to t without overflowing
+//into the next month when the day doesn't exist
+func addMonthsClamped(t time.Time, months int) time.Time {
+
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1,
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+//businessDays counts the Monday to Friday dates
+//in [from, to), ignoring the time of day
+func businessDays(from time.Time, to time.Time) int {
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	totalDays := int(to.Sub(from).Hours() / 24)
+	count := totalDays / 7 * 5
+	for d := from.AddDate(0, 0, totalDays/7*7); d.Before(to); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			count++
+		}
+	}
+
+	return count
+}
+
+//plural formats n with the singular or plural unit
+func plural(n int, singular string, many string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + many
+}
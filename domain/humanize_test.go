@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatTenure(t *testing.T) {
+
+	from := time.Date(2017, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		to       time.Time
+		opts     HumanizeOptions
+		expected string
+	}{
+		{time.Date(2020, 5, 31, 10, 0, 0, 0, time.UTC), HumanizeOptions{}, "3 years, 4 months"},
+		{time.Date(2020, 5, 31, 9, 0, 0, 0, time.UTC), HumanizeOptions{}, "3 years, 3 months"},
+		{time.Date(2020, 5, 31, 9, 0, 0, 0, time.UTC), HumanizeOptions{MaxUnits: 3}, "3 years, 3 months, 30 days"},
+		{time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC), HumanizeOptions{}, "1 month, 1 day"},
+		{time.Date(2017, 1, 31, 20, 0, 0, 0, time.UTC), HumanizeOptions{}, "less than a day"},
+		{time.Date(2018, 1, 31, 10, 0, 0, 0, time.UTC), HumanizeOptions{Locale: &Greek}, "1 έτος"},
+		// Tuesday to the Monday two weeks later
+		{time.Date(2017, 2, 13, 10, 0, 0, 0, time.UTC), HumanizeOptions{BusinessDays: true}, "9 business days"},
+	}
+
+	for _, c := range cases {
+		if got := FormatTenure(from, c.to, c.opts); got != c.expected {
+			t.Errorf("up to %v: expected %q, got %q", c.to, c.expected, got)
+		}
+	}
+}
+
+func TestHumanizeActiveDuration(t *testing.T) {
+
+	now := time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	active := createMockTTEntity(time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC), NilTime())
+	if got := HumanizeActiveDuration(active, now, HumanizeOptions{}); got != "1 year, 2 months" {
+		t.Errorf("unexpected %q", got)
+	}
+
+	ended := createMockTTEntity(time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 25, 0, 0, 0, 0, time.UTC))
+	if got := HumanizeActiveDuration(ended, now, HumanizeOptions{}); got != "10 days" {
+		t.Errorf("unexpected %q", got)
+	}
+
+	if got := HumanizeDuration(36*time.Hour, HumanizeOptions{}); got != "1 day" {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestLifespanStringHumanized(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	open, _ := NewPerson("ann", "Ann", start, NilTime())
+	ended, _ := NewPerson("bob", "Bob", start, start.AddDate(3, 4, 0))
+
+	if s := open.String(); strings.Contains(s, "year") {
+		t.Errorf("expected no length for an ongoing lifespan, got %q", s)
+	}
+	if s := ended.String(); !strings.HasSuffix(s, "2023-05-01T00:00:00Z) 3 years, 4 months") {
+		t.Errorf("expected the humanized length, got %q", s)
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"iter"
+	"sync/atomic"
+	"time"
+)
+
+//All returns an iterator over the entities of the collection
+//in pre order, that is every node is yielded before its
+//subtrees. It is the cheapest walk, for callers that do not
+//care about the order of the entities. The collection must
+//not be modified while iterating
+func (ts *TimeTrackedEntityCollection[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		preOrder(ts.root, yield)
+	}
+}
+
+//InTimeOrder returns an iterator over the entities of the
+//collection ordered by their starting point, as Entities
+//does, without materializing them in a slice. The collection
+//must not be modified while iterating
+func (ts *TimeTrackedEntityCollection[T]) InTimeOrder() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		inOrder(ts.root, yield)
+	}
+}
+
+//Between returns an iterator over the entities that exist at
+//some pit in [from, to), ordered by their starting point. A
+//zero to means the search is open ended, while a to not after
+//from yields nothing. It is the lazy form of FindIntersecting,
+//visiting only the subtrees that may hold matches, and the
+//query path counted in Stats. The collection must not be
+//modified while iterating
+func (ts *TimeTrackedEntityCollection[T]) Between(from time.Time, to time.Time) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if !to.IsZero() && !from.Before(to) {
+			return
+		}
+		visits := 0
+		between(ts.root, from, to, yield, &visits)
+		atomic.AddInt64(&ts.queries, 1)
+		atomic.AddInt64(&ts.visits, int64(visits))
+	}
+}
+
+//preOrder yields n and then its subtrees, returning
+//false as soon as yield asks to stop
+func preOrder[T TimeTrackedEntity](n *intervalNode[T], yield func(T) bool) bool {
+
+	if n == nil {
+		return true
+	}
+
+	return yield(n.entity) && preOrder(n.left, yield) && preOrder(n.right, yield)
+}
+
+//inOrder yields the left subtree of n, n and its right
+//subtree, returning false as soon as yield asks to stop
+func inOrder[T TimeTrackedEntity](n *intervalNode[T], yield func(T) bool) bool {
+
+	if n == nil {
+		return true
+	}
+
+	return inOrder(n.left, yield) && yield(n.entity) && inOrder(n.right, yield)
+}
+
+//between yields in order the entities below n that intersect
+// [from, to), returning false as soon as yield asks to stop,
+//and adds the nodes it visits to visits.
+//Subtrees whose max ending is not after from are pruned, as
+//are the right subtrees of nodes starting at or after to,
+//since everything there starts even later
+func between[T TimeTrackedEntity](n *intervalNode[T], from time.Time, to time.Time, yield func(T) bool, visits *int) bool {
+
+	if n == nil {
+		return true
+	}
+	*visits++
+
+	// nothing below ends after the search starts
+	if !n.max.IsZero() && !n.max.After(from) {
+		return true
+	}
+
+	if !between(n.left, from, to, yield, visits) {
+		return false
+	}
+
+	startsBeforeEnd := to.IsZero() || n.entity.ExistentFrom().Before(to)
+	endsAfterStart := n.entity.ValidUntil().IsZero() || n.entity.ValidUntil().After(from)
+	if startsBeforeEnd && endsAfterStart && !yield(n.entity) {
+		return false
+	}
+
+	return !startsBeforeEnd || between(n.right, from, to, yield, visits)
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIterators(t *testing.T) {
+
+	collection, entities := intersectionFixture()
+
+	var inOrder []TimeTrackedEntity
+	for e := range collection.InTimeOrder() {
+		inOrder = append(inOrder, e)
+	}
+	if len(inOrder) != len(entities) {
+		t.Fatalf("expected %d entities, got %d", len(entities), len(inOrder))
+	}
+	for i := range entities {
+		if inOrder[i] != entities[i] {
+			t.Errorf("expected %v at %d, got %v", entities[i], i, inOrder[i])
+		}
+	}
+
+	seen := make(map[TimeTrackedEntity]bool)
+	for e := range collection.All() {
+		seen[e] = true
+	}
+	if len(seen) != len(entities) {
+		t.Errorf("expected all %d entities, got %d", len(entities), len(seen))
+	}
+
+	from := time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC)
+	var between []TimeTrackedEntity
+	for e := range collection.Between(from, to) {
+		between = append(between, e)
+	}
+	// the entities of [2, open), [4, 6), [5, 10) and [8, open)
+	if len(between) != 4 || between[0] != entities[1] || between[3] != entities[4] {
+		t.Errorf("unexpected entities %v", between)
+	}
+}
+
+func TestBetweenEmptyWindow(t *testing.T) {
+
+	collection := EntityCollection{}
+	collection.AddEntity(createMockTTEntity(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)))
+
+	for _, window := range [][2]time.Time{
+		{time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)},
+	} {
+		for e := range collection.Between(window[0], window[1]) {
+			t.Errorf("[%v, %v): expected no entities, got %v", window[0], window[1], e)
+		}
+		if found := collection.FindIntersecting(window[0], window[1]); len(found) != 0 {
+			t.Errorf("[%v, %v): expected no entities, got %v", window[0], window[1], found)
+		}
+	}
+}
+
+func TestIteratorsStopEarly(t *testing.T) {
+
+	collection, _ := intersectionFixture()
+
+	for name, seq := range map[string]func(func(TimeTrackedEntity) bool){
+		"All":         collection.All(),
+		"InTimeOrder": collection.InTimeOrder(),
+		"Between":     collection.Between(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), NilTime()),
+	} {
+		n := 0
+		for range seq {
+			n++
+			if n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("%s: expected to stop after 2 entities, got %d", name, n)
+		}
+	}
+}
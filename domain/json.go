@@ -0,0 +1,299 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//ErrNoCodec is returned when serializing a collection whose
+//entity type has no codec registered with RegisterCodec
+var ErrNoCodec = errors.New("no codec registered for the entity type")
+
+//codecs holds the registered codecs
+//keyed by the entity type they convert
+var codecs = struct {
+	sync.RWMutex
+	byType map[reflect.Type]interface{}
+}{byType: make(map[reflect.Type]interface{})}
+
+//RegisterCodec installs codec for the collections of T, which
+//use it in MarshalJSON and UnmarshalJSON. For collections of
+//TimeTrackedEntity, such as EntityCollection, the codec must
+//handle every concrete type they hold, telling them apart by
+//EntityRecord.Kind. Registering again for the same type
+//replaces the previous codec
+func RegisterCodec[T TimeTrackedEntity](codec EntityCodec[T]) {
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	codecs.byType[reflect.TypeOf((*T)(nil)).Elem()] = codec
+}
+
+//UnregisterCodec removes the codec installed for T, if any
+func UnregisterCodec[T TimeTrackedEntity]() {
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	delete(codecs.byType, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+//codecFor returns the codec registered for T
+func codecFor[T TimeTrackedEntity]() (EntityCodec[T], error) {
+
+	codecs.RLock()
+	codec, ok := codecs.byType[reflect.TypeOf((*T)(nil)).Elem()]
+	codecs.RUnlock()
+
+	if !ok {
+		var zero T
+		return nil, fmt.Errorf("%w: %v", ErrNoCodec, reflect.TypeOf(&zero).Elem())
+	}
+
+	return codec.(EntityCodec[T]), nil
+}
+
+//MarshalJSON renders the collection as a JSON array of
+//entity records, ordered by their starting point, using
+//the codec registered for T
+func (ts TimeTrackedEntityCollection[T]) MarshalJSON() ([]byte, error) {
+
+	codec, err := codecFor[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]EntityRecord, 0, ts.noOfNodes)
+	for e := range ts.InTimeOrder() {
+		record, err := codec.Encode(e)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return json.Marshal(records)
+}
+
+//UnmarshalJSON replaces the entities of the collection with
+//the ones decoded from a JSON array of entity records, using
+//the codec registered for T. The tree is built balanced
+func (ts *TimeTrackedEntityCollection[T]) UnmarshalJSON(data []byte) error {
+
+	codec, err := codecFor[T]()
+	if err != nil {
+		return err
+	}
+
+	var records []EntityRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	entities := make([]T, len(records))
+	for i, record := range records {
+		if entities[i], err = codec.Decode(record); err != nil {
+			return fmt.Errorf("entity %s: %v", record.ID, err)
+		}
+	}
+
+	threshold := ts.rebuildThreshold
+	*ts = *NewCollectionFromEntities(entities)
+	ts.rebuildThreshold = threshold
+
+	return nil
+}
+
+//jsonRecord is the JSON form of an EntityRecord
+type jsonRecord struct {
+	ID         string                     `json:"id"`
+	Kind       string                     `json:"kind,omitempty"`
+	Name       string                     `json:"name,omitempty"`
+	From       time.Time                  `json:"from"`
+	Until      *time.Time                 `json:"until"`
+	Links      map[string]string          `json:"links,omitempty"`
+	Attributes map[string]json.RawMessage `json:"attributes,omitempty"`
+	// the types of the attributes that JSON
+	// can't tell apart, keyed by attribute name
+	Types   map[string]string `json:"types,omitempty"`
+	History []jsonValue       `json:"history,omitempty"`
+}
+
+//jsonValue is the JSON form of an AttributeValue
+type jsonValue struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+	Type  string          `json:"type,omitempty"`
+	From  time.Time       `json:"from"`
+	Until *time.Time      `json:"until"`
+}
+
+//timeType marks the values that are times,
+//which JSON would otherwise turn to strings
+const timeType = "time"
+
+//MarshalJSON renders the record as a JSON object. An open
+//end is written as a null until, pits are written in RFC
+// 3339 and attribute values in their JSON form, marking the
+//ones that are times so they come back as times
+func (r EntityRecord) MarshalJSON() ([]byte, error) {
+
+	encoded := jsonRecord{ID: r.ID, Kind: r.Kind, Name: r.Name, From: r.From, Until: optionalTime(r.Until), Links: r.Links}
+	if len(r.Attributes) > 0 {
+		encoded.Attributes = make(map[string]json.RawMessage, len(r.Attributes))
+		for name, value := range r.Attributes {
+			raw, typ, err := encodeValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: %v", name, err)
+			}
+			encoded.Attributes[name] = raw
+			if typ != "" {
+				if encoded.Types == nil {
+					encoded.Types = make(map[string]string)
+				}
+				encoded.Types[name] = typ
+			}
+		}
+	}
+	for _, v := range r.History {
+		raw, typ, err := encodeValue(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %v", v.Name, err)
+		}
+		encoded.History = append(encoded.History, jsonValue{Name: v.Name, Value: raw, Type: typ, From: v.From, Until: optionalTime(v.Until)})
+	}
+
+	return json.Marshal(encoded)
+}
+
+//UnmarshalJSON reads a record written by MarshalJSON. A null
+//or missing until stands for an open end. Attribute values
+//come back as their JSON types, except for numbers without
+//a fraction or exponent that fit in an int, which come back
+//as ints, so integer attributes survive the round trip, and
+//times, which come back as times
+func (r *EntityRecord) UnmarshalJSON(data []byte) error {
+
+	var decoded jsonRecord
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*r = EntityRecord{ID: decoded.ID, Kind: decoded.Kind, Name: decoded.Name, From: decoded.From, Links: decoded.Links}
+	if decoded.Until != nil {
+		r.Until = *decoded.Until
+	}
+	if len(decoded.Attributes) > 0 {
+		r.Attributes = make(map[string]interface{}, len(decoded.Attributes))
+		for name, raw := range decoded.Attributes {
+			value, err := decodeValue(raw, decoded.Types[name])
+			if err != nil {
+				return fmt.Errorf("attribute %s: %v", name, err)
+			}
+			r.Attributes[name] = value
+		}
+	}
+	for _, v := range decoded.History {
+		value, err := decodeValue(v.Value, v.Type)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %v", v.Name, err)
+		}
+		restored := AttributeValue{Name: v.Name, Value: value, From: v.From}
+		if v.Until != nil {
+			restored.Until = *v.Until
+		}
+		r.History = append(r.History, restored)
+	}
+
+	return nil
+}
+
+//optionalTime returns nil for the zero time,
+//which stands for an open end
+func optionalTime(t time.Time) *time.Time {
+
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+//encodeValue returns the JSON form of an attribute
+//value, along with its type if JSON can't tell it
+func encodeValue(value interface{}) (json.RawMessage, string, error) {
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, ok := value.(time.Time); ok {
+		return raw, timeType, nil
+	}
+
+	return raw, "", nil
+}
+
+//decodeValue reads an attribute value written
+//by encodeValue, see DecodeAttribute
+func decodeValue(raw json.RawMessage, typ string) (interface{}, error) {
+
+	switch typ {
+	case "":
+		return DecodeAttribute(raw)
+	case timeType:
+		var t time.Time
+		err := json.Unmarshal(raw, &t)
+		return t, err
+	}
+
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+//DecodeAttribute decodes an attribute value from its JSON
+//form the way EntityRecord.UnmarshalJSON does, turning
+//integral numbers that fit in an int to ints. It is meant
+//for storage backends keeping attribute values as JSON
+func DecodeAttribute(raw []byte) (interface{}, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return fromNumbers(value), nil
+}
+
+//fromNumbers replaces the json.Numbers within value
+//with ints, or float64s if they are not integral
+func fromNumbers(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil && n >= math.MinInt && n <= math.MaxInt {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = fromNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = fromNumbers(v[k])
+		}
+	}
+
+	return value
+}
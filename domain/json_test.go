@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockCodec converts the mock entities of the tests
+type mockCodec struct{}
+
+func (mockCodec) Encode(e TimeTrackedEntity) (EntityRecord, error) {
+	switch m := e.(type) {
+	case mockTTEntity:
+		return EntityRecord{ID: m.id, Kind: "plain", From: m.startFrom, Until: m.endAt}, nil
+	case mockAttributedEntity:
+		return EntityRecord{ID: m.id, Kind: "attributed", From: m.startFrom, Until: m.endAt, Attributes: Attributes(m)}, nil
+	}
+	return EntityRecord{}, fmt.Errorf("unsupported entity %T", e)
+}
+
+func (mockCodec) Decode(r EntityRecord) (TimeTrackedEntity, error) {
+	plain := mockTTEntity{id: r.ID, startFrom: r.From, endAt: r.Until}
+	switch r.Kind {
+	case "plain":
+		return plain, nil
+	case "attributed":
+		return mockAttributedEntity{mockTTEntity: plain, attributes: r.Attributes}, nil
+	}
+	return nil, fmt.Errorf("unsupported kind %q", r.Kind)
+}
+
+func TestCollectionJSON(t *testing.T) {
+
+	collection := EntityCollection{}
+	if _, err := json.Marshal(collection); !errors.Is(err, ErrNoCodec) {
+		t.Errorf("expected ErrNoCodec, got %v", err)
+	}
+
+	RegisterCodec[TimeTrackedEntity](mockCodec{})
+	t.Cleanup(UnregisterCodec[TimeTrackedEntity])
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	collection.AddEntity(createMockTTEntity(start, start.AddDate(0, 1, 0)))
+	collection.AddEntity(createMockAttributedEntity(start.AddDate(0, 0, 1), NilTime(), map[string]interface{}{
+		"grade":  7,
+		"name":   "Ann",
+		"ratio":  0.5,
+		"active": true,
+		"skills": []interface{}{"go", 3},
+		"hired":  time.Date(2019, 12, 15, 9, 0, 0, 0, time.UTC),
+	}))
+
+	data, err := json.Marshal(&collection)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(string(data), `"until":null`) || !strings.Contains(string(data), `"until":"2020-02-01T00:00:00Z"`) {
+		t.Errorf("expected the open end as null, got %s", data)
+	}
+
+	decoded := EntityCollection{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if mismatches := Compare(&collection, &decoded, nil); len(mismatches) != 0 {
+		t.Errorf("expected the collection to round trip, got %v", mismatches)
+	}
+	if err := decoded.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+}
+
+func TestTypedCollectionJSON(t *testing.T) {
+
+	RegisterCodec[*AttributeValue](attributeValueCodec{})
+	t.Cleanup(UnregisterCodec[*AttributeValue])
+
+	b := &BaseTemporalAttributeBearer{}
+	b.SetAttributeAt("band", "B1", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b.SetAttributeAt("band", "B2", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	data, err := json.Marshal(b.history["band"])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var decoded TimeTrackedEntityCollection[*AttributeValue]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if values := decoded.Entities(); len(values) != 2 || values[1].Value != "B2" || !values[1].Until.IsZero() {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"id": "x", "from": "2020-01-01T00:00:00Z"}]`), &decoded); err == nil {
+		t.Error("expected the decoding error of the codec")
+	}
+}
+
+// attributeValueCodec keeps the value in a single attribute
+type attributeValueCodec struct{}
+
+func (attributeValueCodec) Encode(v *AttributeValue) (EntityRecord, error) {
+	return EntityRecord{ID: v.Name, From: v.From, Until: v.Until, Attributes: map[string]interface{}{"value": v.Value}}, nil
+}
+
+func (attributeValueCodec) Decode(r EntityRecord) (*AttributeValue, error) {
+	value, ok := r.Attributes["value"]
+	if !ok {
+		return nil, errors.New("missing value")
+	}
+	return &AttributeValue{Name: r.ID, Value: value, From: r.From, Until: r.Until}, nil
+}
@@ -0,0 +1,504 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NTsiridis/orgopus/clock"
+)
+
+// --------------------  Organizational model ------------------
+
+//ErrMissingStart is returned when creating an
+//entity without a starting pit
+var ErrMissingStart = errors.New("entity must have a start")
+
+//ErrOutsideParent is returned when an entity would exist
+//outside the lifespan of the entity it belongs to
+var ErrOutsideParent = errors.New("entity exists outside its parent")
+
+//ErrUnitCycle is returned when moving a unit
+//below itself or one of its descendants
+var ErrUnitCycle = errors.New("unit cannot be placed below itself")
+
+//ErrOtherOrganization is returned when linking
+//units that belong to different organizations
+var ErrOtherOrganization = errors.New("unit belongs to another organization")
+
+//lifespan is the interval an entity of the org model
+//exists in. It implements TimeTrackedEntity and
+//EndableEntity for the types embedding it
+type lifespan struct {
+	from  time.Time
+	until time.Time
+}
+
+//newLifespan validates the interval of a new entity
+func newLifespan(from time.Time, until time.Time) (lifespan, error) {
+
+	if from.IsZero() {
+		return lifespan{}, ErrMissingStart
+	}
+	if !until.IsZero() && !until.After(from) {
+		return lifespan{}, ErrEndBeforeStart
+	}
+
+	return lifespan{from: from, until: until}, nil
+}
+
+//IsExistentAt checks if the entity exists at pit
+func (l *lifespan) IsExistentAt(pit time.Time) bool {
+	return !l.from.After(pit) && (l.until.IsZero() || l.until.After(pit))
+}
+
+//ExistentFrom returns the pit the entity came into existence
+func (l *lifespan) ExistentFrom() time.Time {
+	return l.from
+}
+
+//ValidUntil returns the pit the entity stopped existing,
+//or the zero time if it still exists
+func (l *lifespan) ValidUntil() time.Time {
+	return l.until
+}
+
+//ActiveDuration returns how long the entity existed, up
+//to now if it still exists, as told by clock.Now
+func (l *lifespan) ActiveDuration() time.Duration {
+
+	until := l.until
+	if until.IsZero() {
+		until = clock.Now()
+	}
+
+	return until.Sub(l.from)
+}
+
+//End terminates the entity at the given pit
+func (l *lifespan) End(at time.Time) error {
+	return l.endContaining(at, nil)
+}
+
+//endContaining terminates the entity at the given pit,
+//provided the lifespans of the entities belonging to it
+//still fit in it, otherwise ErrOutsideParent is returned
+func (l *lifespan) endContaining(at time.Time, inner []*lifespan) error {
+
+	if !l.until.IsZero() {
+		return ErrEntityAlreadyEnded
+	}
+	if !at.After(l.from) {
+		return ErrEndBeforeStart
+	}
+
+	ended := lifespan{from: l.from, until: at}
+	for _, i := range inner {
+		if !i.within(ended) {
+			return ErrOutsideParent
+		}
+	}
+	l.until = at
+
+	return nil
+}
+
+//within checks that l starts no earlier and
+//ends no later than outer
+func (l lifespan) within(outer lifespan) bool {
+
+	if l.from.Before(outer.from) {
+		return false
+	}
+	if outer.until.IsZero() {
+		return true
+	}
+
+	return !l.until.IsZero() && !l.until.After(outer.until)
+}
+
+//String renders the interval as [from, until), followed
+//by its humanized length if it has ended, see FormatTenure
+func (l lifespan) String() string {
+
+	span := fmt.Sprintf("[%s, %s)", canonicalTime(l.from), canonicalTime(l.until))
+	if l.until.IsZero() {
+		return span
+	}
+
+	return span + " " + FormatTenure(l.from, l.until, HumanizeOptions{})
+}
+
+//Organization is the top level entity of the model,
+//owning a hierarchy of units
+type Organization struct {
+	lifespan
+	BaseTemporalAttributeBearer
+	ID   string
+	Name string
+
+	roots []*OrgUnit
+	// set once a unit is moved with MoveAt, so the
+	// hierarchy is known to change over time
+	moved bool
+}
+
+//NewOrganization creates an organization existing from the
+//given pit until the given one, which may be zero for an
+//organization that has not ended
+func NewOrganization(id string, name string, from time.Time, until time.Time) (*Organization, error) {
+
+	span, err := newLifespan(from, until)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Organization{lifespan: span, ID: id, Name: name}, nil
+}
+
+//RootUnits returns the units of the
+//organization that have no parent
+func (o *Organization) RootUnits() []*OrgUnit {
+	return append([]*OrgUnit(nil), o.roots...)
+}
+
+//units returns every unit of the organization, in
+//pre order of the current hierarchy
+func (o *Organization) units() []*OrgUnit {
+
+	var units []*OrgUnit
+	var visit func(u *OrgUnit)
+	visit = func(u *OrgUnit) {
+		units = append(units, u)
+		for _, child := range u.children {
+			visit(child)
+		}
+	}
+	for _, root := range o.roots {
+		visit(root)
+	}
+
+	return units
+}
+
+//movedUnits returns the units of the organization that
+//may have been moved with MoveAt, none if no unit was
+func (o *Organization) movedUnits() []*OrgUnit {
+
+	if !o.moved {
+		return nil
+	}
+
+	return o.units()
+}
+
+//End terminates the organization at the given pit. Its units
+//must have ended by then, otherwise ErrOutsideParent is
+//returned and the organization is left as it was
+func (o *Organization) End(at time.Time) error {
+
+	inner := make([]*lifespan, 0, len(o.roots))
+	for _, u := range o.roots {
+		inner = append(inner, &u.lifespan)
+	}
+
+	return o.endContaining(at, inner)
+}
+
+//String implementation of an organization
+func (o *Organization) String() string {
+	return fmt.Sprintf("organization %s %s", o.ID, o.lifespan)
+}
+
+//OrgUnit is a unit of an organization, such as a division
+//or a team. Units form a hierarchy below the organization
+type OrgUnit struct {
+	lifespan
+	BaseTemporalAttributeBearer
+	ID   string
+	Name string
+
+	organization *Organization
+	parent       *OrgUnit
+	children     []*OrgUnit
+	positions    []*Position
+	// the parents the unit had before it was
+	// moved with MoveAt, ordered by until
+	moves []unitMove
+}
+
+//unitMove is a parent a unit had until it was moved,
+//nil if the unit was at the top of the hierarchy
+type unitMove struct {
+	parent *OrgUnit
+	until  time.Time
+}
+
+//NewOrgUnit creates a unit of org placed below parent, or at
+//the top of the hierarchy if parent is nil. The unit must
+//exist within the lifespan of its parent and organization,
+//otherwise ErrOutsideParent is returned
+func NewOrgUnit(org *Organization, parent *OrgUnit, id string, name string, from time.Time, until time.Time) (*OrgUnit, error) {
+
+	span, err := newLifespan(from, until)
+	if err != nil {
+		return nil, err
+	}
+	if !span.within(org.lifespan) {
+		return nil, ErrOutsideParent
+	}
+
+	unit := &OrgUnit{lifespan: span, ID: id, Name: name, organization: org}
+	if err := unit.SetParent(parent); err != nil {
+		return nil, err
+	}
+
+	return unit, nil
+}
+
+//Organization returns the organization the unit belongs to
+func (u *OrgUnit) Organization() *Organization {
+	return u.organization
+}
+
+//Parent returns the unit directly above u,
+//or nil if u is a root unit
+func (u *OrgUnit) Parent() *OrgUnit {
+	return u.parent
+}
+
+//ParentAt returns the unit directly above u at pit, which
+//differs from Parent before u was moved with MoveAt, or nil
+//if u was at the top of the hierarchy then
+func (u *OrgUnit) ParentAt(pit time.Time) *OrgUnit {
+
+	for _, m := range u.moves {
+		if pit.Before(m.until) {
+			return m.parent
+		}
+	}
+
+	return u.parent
+}
+
+//Children returns the units directly below u
+func (u *OrgUnit) Children() []*OrgUnit {
+	return append([]*OrgUnit(nil), u.children...)
+}
+
+//Positions returns the positions of the unit
+func (u *OrgUnit) Positions() []*Position {
+	return append([]*Position(nil), u.positions...)
+}
+
+//SetParent places u below parent, or at the top of the
+//hierarchy if parent is nil, along with its descendants, for
+//the whole lifespan of u, as if it had always been there.
+//Earlier moves are forgotten, so use MoveAt to move a unit
+//from a pit on. The parent must belong to the same
+//organization, must not be u or one of its descendants and
+//must exist during the whole lifespan of u
+func (u *OrgUnit) SetParent(parent *OrgUnit) error {
+
+	if err := u.checkParent(parent, u.lifespan); err != nil {
+		return err
+	}
+	u.moves = nil
+	u.link(parent)
+
+	return nil
+}
+
+//MoveAt moves u below parent, or to the top of the hierarchy
+//if parent is nil, along with its descendants, from the
+//given pit on. Before it, u stays below the parents it had,
+//see ParentAt, and moves of u after it are forgotten. Moving
+//at or before the start of u is the same as SetParent. The
+//parent must belong to the same organization, must not be u
+//or one of its descendants and must exist during the rest of
+//the lifespan of u, which must be existent at the pit
+func (u *OrgUnit) MoveAt(parent *OrgUnit, at time.Time) error {
+
+	if !at.After(u.from) {
+		return u.SetParent(parent)
+	}
+	if !u.IsExistentAt(at) {
+		return ErrOutsideParent
+	}
+	if err := u.checkParent(parent, lifespan{from: at, until: u.until}); err != nil {
+		return err
+	}
+
+	previous := u.ParentAt(at)
+	kept := 0
+	for kept < len(u.moves) && !u.moves[kept].until.After(at) {
+		kept++
+	}
+	u.moves = u.moves[:kept]
+	if previous != parent {
+		u.moves = append(u.moves, unitMove{parent: previous, until: at})
+		u.organization.moved = true
+	}
+	u.link(parent)
+
+	return nil
+}
+
+//checkParent checks that u can be placed below parent during
+//span: the parent belongs to the same organization, exists
+//during span and is not below u at any pit of it
+func (u *OrgUnit) checkParent(parent *OrgUnit, span lifespan) error {
+
+	if parent == nil {
+		return nil
+	}
+	if parent.organization != u.organization {
+		return ErrOtherOrganization
+	}
+	// the hierarchy only changes at the pits units are moved at
+	pits := []time.Time{span.from}
+	for _, unit := range u.organization.movedUnits() {
+		for _, m := range unit.moves {
+			if m.until.After(span.from) && (span.until.IsZero() || m.until.Before(span.until)) {
+				pits = append(pits, m.until)
+			}
+		}
+	}
+	for _, pit := range pits {
+		for p := parent; p != nil; p = p.ParentAt(pit) {
+			if p == u {
+				return ErrUnitCycle
+			}
+		}
+	}
+	if !span.within(parent.lifespan) {
+		return ErrOutsideParent
+	}
+
+	return nil
+}
+
+//link makes parent the current parent of u
+func (u *OrgUnit) link(parent *OrgUnit) {
+
+	if u.parent != nil {
+		u.parent.children = removeUnit(u.parent.children, u)
+	} else {
+		u.organization.roots = removeUnit(u.organization.roots, u)
+	}
+
+	u.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, u)
+	} else {
+		u.organization.roots = append(u.organization.roots, u)
+	}
+}
+
+//End terminates the unit at the given pit. Its child units,
+//including the ones moved away from it, and positions must
+//have ended by then, otherwise ErrOutsideParent is returned
+//and the unit is left as it was
+func (u *OrgUnit) End(at time.Time) error {
+
+	inner := make([]*lifespan, 0, len(u.children)+len(u.positions))
+	for _, child := range u.children {
+		inner = append(inner, &child.lifespan)
+	}
+	for _, unit := range u.organization.movedUnits() {
+		for _, m := range unit.moves {
+			if m.parent == u {
+				inner = append(inner, &lifespan{from: unit.from, until: m.until})
+			}
+		}
+	}
+	for _, p := range u.positions {
+		inner = append(inner, &p.lifespan)
+	}
+
+	return u.endContaining(at, inner)
+}
+
+//String implementation of a unit
+func (u *OrgUnit) String() string {
+	return fmt.Sprintf("unit %s %s", u.ID, u.lifespan)
+}
+
+//removeUnit returns units without u
+func removeUnit(units []*OrgUnit, u *OrgUnit) []*OrgUnit {
+
+	for i, unit := range units {
+		if unit == u {
+			return append(units[:i:i], units[i+1:]...)
+		}
+	}
+
+	return units
+}
+
+//Position is a role within a unit, such as
+// "Head of Sales", that people are assigned to
+type Position struct {
+	lifespan
+	BaseTemporalAttributeBearer
+	ID    string
+	Title string
+
+	unit *OrgUnit
+}
+
+//NewPosition creates a position of unit. The position
+//must exist within the lifespan of the unit, otherwise
+//ErrOutsideParent is returned
+func NewPosition(unit *OrgUnit, id string, title string, from time.Time, until time.Time) (*Position, error) {
+
+	span, err := newLifespan(from, until)
+	if err != nil {
+		return nil, err
+	}
+	if !span.within(unit.lifespan) {
+		return nil, ErrOutsideParent
+	}
+
+	position := &Position{lifespan: span, ID: id, Title: title, unit: unit}
+	unit.positions = append(unit.positions, position)
+
+	return position, nil
+}
+
+//Unit returns the unit the position belongs to
+func (p *Position) Unit() *OrgUnit {
+	return p.unit
+}
+
+//String implementation of a position
+func (p *Position) String() string {
+	return fmt.Sprintf("position %s %s", p.ID, p.lifespan)
+}
+
+//Person is someone who works, or has worked, for the
+//organization. Their lifespan is the period they are
+//known to the model, for example their employment
+type Person struct {
+	lifespan
+	BaseTemporalAttributeBearer
+	ID   string
+	Name string
+}
+
+//NewPerson creates a person known to the model from
+//the given pit until the given one, which may be zero
+func NewPerson(id string, name string, from time.Time, until time.Time) (*Person, error) {
+
+	span, err := newLifespan(from, until)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Person{lifespan: span, ID: id, Name: name}, nil
+}
+
+//String implementation of a person
+func (p *Person) String() string {
+	return fmt.Sprintf("person %s %s", p.ID, p.lifespan)
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/clock"
+)
+
+func TestOrgModelConstructors(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	if _, err := NewPerson("p1", "Ann", NilTime(), NilTime()); err != ErrMissingStart {
+		t.Errorf("expected ErrMissingStart, got %v", err)
+	}
+	if _, err := NewPerson("p1", "Ann", day(2), day(2)); err != ErrEndBeforeStart {
+		t.Errorf("expected ErrEndBeforeStart, got %v", err)
+	}
+
+	org, err := NewOrganization("acme", "Acme", day(1), NilTime())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sales, err := NewOrgUnit(org, nil, "sales", "Sales", day(1), day(20))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := NewOrgUnit(org, sales, "emea", "EMEA", day(2), NilTime()); err != ErrOutsideParent {
+		t.Errorf("expected an open ended child of an ending unit to be rejected, got %v", err)
+	}
+	emea, err := NewOrgUnit(org, sales, "emea", "EMEA", day(2), day(10))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := NewPosition(emea, "head", "Head of EMEA", day(1), NilTime()); err != ErrOutsideParent {
+		t.Errorf("expected a position starting before its unit to be rejected, got %v", err)
+	}
+	head, err := NewPosition(emea, "head", "Head of EMEA", day(2), day(10))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if roots := org.RootUnits(); len(roots) != 1 || roots[0] != sales {
+		t.Errorf("unexpected roots %v", roots)
+	}
+	if children := sales.Children(); len(children) != 1 || children[0] != emea || emea.Parent() != sales {
+		t.Errorf("unexpected children %v", children)
+	}
+	if positions := emea.Positions(); len(positions) != 1 || positions[0] != head || head.Unit() != emea {
+		t.Errorf("unexpected positions %v", positions)
+	}
+
+	var _ AttributeBearer = head
+	var _ EndableEntity = head
+	collection := TimeTrackedEntityCollection[*OrgUnit]{}
+	collection.AddEntity(sales)
+	collection.AddEntity(emea)
+	if found := collection.FindExistentAt(day(15)); len(found) != 1 || found[0] != sales {
+		t.Errorf("unexpected units %v", found)
+	}
+}
+
+func TestOrgUnitSetParent(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	org, _ := NewOrganization("acme", "Acme", start, NilTime())
+	other, _ := NewOrganization("globex", "Globex", start, NilTime())
+	a, _ := NewOrgUnit(org, nil, "a", "A", start, NilTime())
+	b, _ := NewOrgUnit(org, a, "b", "B", start, NilTime())
+	c, _ := NewOrgUnit(org, nil, "c", "C", start, NilTime())
+	foreign, _ := NewOrgUnit(other, nil, "x", "X", start, NilTime())
+
+	if err := a.SetParent(b); err != ErrUnitCycle {
+		t.Errorf("expected ErrUnitCycle, got %v", err)
+	}
+	if err := a.SetParent(foreign); err != ErrOtherOrganization {
+		t.Errorf("expected ErrOtherOrganization, got %v", err)
+	}
+
+	if err := b.SetParent(c); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(a.Children()) != 0 || len(c.Children()) != 1 || b.Parent() != c {
+		t.Error("expected b to move below c")
+	}
+	if err := b.SetParent(nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if roots := org.RootUnits(); len(roots) != 3 || b.Parent() != nil || len(c.Children()) != 0 {
+		t.Errorf("expected b to become a root, got %v", roots)
+	}
+}
+
+func TestOrgEndKeepsContainment(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	org, _ := NewOrganization("acme", "Acme", day(1), NilTime())
+	sales, _ := NewOrgUnit(org, nil, "sales", "Sales", day(1), NilTime())
+	NewOrgUnit(org, sales, "emea", "EMEA", day(1), day(10))
+	head, _ := NewPosition(sales, "head", "Head of Sales", day(1), NilTime())
+	ann, _ := NewPerson("ann", "Ann", day(1), NilTime())
+	assignment, _ := NewAssignment(ann, head, day(1), NilTime())
+	assignments := AssignmentCollection{}
+	assignments.AddAssignment(assignment)
+
+	units := TimeTrackedEntityCollection[*OrgUnit]{}
+	units.AddEntity(sales)
+	if err := units.EndEntity(sales, day(20)); err != ErrOutsideParent {
+		t.Errorf("expected the open ended position to keep the unit open, got %v", err)
+	}
+	if !sales.ValidUntil().IsZero() || len(units.FindExistentAt(day(30))) != 1 {
+		t.Error("expected the unit to be left as it was")
+	}
+	if err := org.End(day(20)); err != ErrOutsideParent {
+		t.Errorf("expected the open ended unit to keep the organization open, got %v", err)
+	}
+
+	if err := assignments.EndPosition(head, day(20)); err != ErrOutsideParent {
+		t.Errorf("expected the open ended assignment to keep the position open, got %v", err)
+	}
+	if err := assignments.EndPerson(ann, day(20)); err != ErrOutsideParent {
+		t.Errorf("expected the open ended assignment to keep the person open, got %v", err)
+	}
+	if err := assignments.EndAssignment(assignment, day(15)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := assignments.EndPosition(head, day(20)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := assignments.EndPerson(ann, day(15)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the child unit ended before day 9
+	if err := sales.End(day(9)); err != ErrOutsideParent {
+		t.Errorf("expected the child unit to keep the unit open, got %v", err)
+	}
+	if err := units.EndEntity(sales, day(20)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := org.End(day(20)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOrgActiveDurationClock(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	restore := clock.Set(clock.NewSimulated(start.AddDate(0, 0, 10)))
+	defer restore()
+
+	org, err := NewOrganization("acme", "Acme", start, NilTime())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	person, err := NewPerson("ann", "Ann", start.AddDate(0, 0, 4), NilTime())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if d := org.ActiveDuration(); d != 10*24*time.Hour {
+		t.Errorf("expected 10 days up to the simulated now, got %v", d)
+	}
+	if d := person.ActiveDuration(); d != 6*24*time.Hour {
+		t.Errorf("expected 6 days up to the simulated now, got %v", d)
+	}
+}
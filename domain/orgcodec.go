@@ -0,0 +1,310 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//The kinds of the records of OrgCodec
+const (
+	KindOrganization = "organization"
+	KindUnit         = "unit"
+	KindPosition     = "position"
+	KindPerson       = "person"
+	KindAssignment   = "assignment"
+)
+
+//OrgCodec is the EntityCodec of the organizational model:
+//organizations, units, positions, persons and assignments.
+//Records keep the whole attribute history of the entities and
+//link them to the entities they belong to by ID, units to
+//their current parent under "parent" and to the parents they
+//were moved from under "parent until" and the pit they were
+//moved at, an empty ID standing for the top of the hierarchy.
+//Decoding
+//rebuilds those links, so the records of a model must all be
+//decoded by the same codec, in any order. The entities a
+//record links to are created when first referred to and
+//filled in when their own record is decoded, see Missing. A
+//codec is meant to load a single model, so use a new one for
+//every model. It is safe for concurrent use
+type OrgCodec struct {
+	mu       sync.Mutex
+	entities map[string]TimeTrackedEntity
+	decoded  map[string]bool
+}
+
+var _ EntityCodec[TimeTrackedEntity] = (*OrgCodec)(nil)
+
+//NewOrgCodec creates a codec of the organizational model
+func NewOrgCodec() *OrgCodec {
+	return &OrgCodec{
+		entities: make(map[string]TimeTrackedEntity),
+		decoded:  make(map[string]bool),
+	}
+}
+
+//Encode implementation of EntityCodec. Assignments have no
+//ID of their own, they are identified by their person,
+//position and start
+func (c *OrgCodec) Encode(e TimeTrackedEntity) (EntityRecord, error) {
+
+	switch v := e.(type) {
+	case *Organization:
+		return spanRecord(KindOrganization, v.ID, v.Name, v.lifespan, v, nil), nil
+
+	case *OrgUnit:
+		links := map[string]string{KindOrganization: v.organization.ID}
+		if v.parent != nil {
+			links["parent"] = v.parent.ID
+		}
+		for _, m := range v.moves {
+			id := ""
+			if m.parent != nil {
+				id = m.parent.ID
+			}
+			links[movedPrefix+canonicalTime(m.until)] = id
+		}
+		return spanRecord(KindUnit, v.ID, v.Name, v.lifespan, v, links), nil
+
+	case *Position:
+		links := map[string]string{KindUnit: v.unit.ID}
+		return spanRecord(KindPosition, v.ID, v.Title, v.lifespan, v, links), nil
+
+	case *Person:
+		return spanRecord(KindPerson, v.ID, v.Name, v.lifespan, v, nil), nil
+
+	case *Assignment:
+		links := map[string]string{KindPerson: v.Person.ID, KindPosition: v.Position.ID}
+		return spanRecord(KindAssignment, assignmentID(v), "", v.lifespan, v, links), nil
+	}
+
+	return EntityRecord{}, fmt.Errorf("unsupported entity %T", e)
+}
+
+//Decode implementation of EntityCodec
+func (c *OrgCodec) Decode(r EntityRecord) (TimeTrackedEntity, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := r.Kind + " " + r.ID
+	if c.decoded[key] {
+		return nil, fmt.Errorf("%s decoded twice", key)
+	}
+
+	span, err := newLifespan(r.From, r.Until)
+	if err != nil {
+		return nil, err
+	}
+	// restored up front, so a failing record
+	// leaves the entities decoded so far intact
+	var history BaseTemporalAttributeBearer
+	if err := history.RestoreHistory(r.History); err != nil {
+		return nil, err
+	}
+
+	var e TimeTrackedEntity
+	switch r.Kind {
+	case KindOrganization:
+		o := c.organization(r.ID)
+		o.lifespan, o.Name = span, r.Name
+		o.BaseTemporalAttributeBearer = history
+		e = o
+
+	case KindUnit:
+		if r.Links[KindOrganization] == "" {
+			return nil, fmt.Errorf("missing %s link", KindOrganization)
+		}
+		moves, err := decodeMoves(r.Links)
+		if err != nil {
+			return nil, err
+		}
+		u := c.unit(r.ID)
+		u.lifespan, u.Name = span, r.Name
+		u.organization = c.organization(r.Links[KindOrganization])
+		if parent := r.Links["parent"]; parent != "" {
+			u.parent = c.unit(parent)
+			u.parent.children = append(u.parent.children, u)
+		} else {
+			u.organization.roots = append(u.organization.roots, u)
+		}
+		for _, m := range moves {
+			var parent *OrgUnit
+			if m.id != "" {
+				parent = c.unit(m.id)
+			}
+			u.moves = append(u.moves, unitMove{parent: parent, until: m.until})
+			u.organization.moved = true
+		}
+		u.BaseTemporalAttributeBearer = history
+		e = u
+
+	case KindPosition:
+		if r.Links[KindUnit] == "" {
+			return nil, fmt.Errorf("missing %s link", KindUnit)
+		}
+		p := c.position(r.ID)
+		p.lifespan, p.Title = span, r.Name
+		p.unit = c.unit(r.Links[KindUnit])
+		p.unit.positions = append(p.unit.positions, p)
+		p.BaseTemporalAttributeBearer = history
+		e = p
+
+	case KindPerson:
+		p := c.person(r.ID)
+		p.lifespan, p.Name = span, r.Name
+		p.BaseTemporalAttributeBearer = history
+		e = p
+
+	case KindAssignment:
+		if r.Links[KindPerson] == "" || r.Links[KindPosition] == "" {
+			return nil, fmt.Errorf("missing %s or %s link", KindPerson, KindPosition)
+		}
+		a := &Assignment{lifespan: span, Person: c.person(r.Links[KindPerson]), Position: c.position(r.Links[KindPosition])}
+		a.BaseTemporalAttributeBearer = history
+		e = a
+
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", r.Kind)
+	}
+
+	c.decoded[key] = true
+
+	return e, nil
+}
+
+//movedPrefix starts the links of units
+//to the parents they were moved from
+const movedPrefix = "parent until "
+
+//decodedMove is a unitMove read from the
+//links of a record, before it is linked
+type decodedMove struct {
+	id    string
+	until time.Time
+}
+
+//decodeMoves reads the parents a unit was moved
+//from from its links, ordered by until
+func decodeMoves(links map[string]string) ([]decodedMove, error) {
+
+	var moves []decodedMove
+	for key, id := range links {
+		at, ok := strings.CutPrefix(key, movedPrefix)
+		if !ok {
+			continue
+		}
+		until, err := time.Parse(time.RFC3339Nano, at)
+		if err != nil {
+			return nil, fmt.Errorf("link %q: %v", key, err)
+		}
+		moves = append(moves, decodedMove{id: id, until: until})
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].until.Before(moves[j].until)
+	})
+
+	return moves, nil
+}
+
+//Missing returns the entities that decoded records link to,
+//but whose own records were not decoded, as "kind ID" sorted.
+//Those entities have no lifespan, so a model is complete only
+//once Missing is empty
+func (c *OrgCodec) Missing() []string {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var missing []string
+	for key := range c.entities {
+		if !c.decoded[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+//organization returns the organization with the
+//given ID, creating it when first referred to
+func (c *OrgCodec) organization(id string) *Organization {
+	return linked(c, KindOrganization, id, func() *Organization { return &Organization{ID: id} })
+}
+
+//unit returns the unit with the given ID,
+//creating it when first referred to
+func (c *OrgCodec) unit(id string) *OrgUnit {
+	return linked(c, KindUnit, id, func() *OrgUnit { return &OrgUnit{ID: id} })
+}
+
+//position returns the position with the given
+//ID, creating it when first referred to
+func (c *OrgCodec) position(id string) *Position {
+	return linked(c, KindPosition, id, func() *Position { return &Position{ID: id} })
+}
+
+//person returns the person with the given ID,
+//creating it when first referred to
+func (c *OrgCodec) person(id string) *Person {
+	return linked(c, KindPerson, id, func() *Person { return &Person{ID: id} })
+}
+
+//linked returns the entity of kind with the given ID,
+//creating it with create when first referred to. It
+//expects the lock of c held
+func linked[T TimeTrackedEntity](c *OrgCodec, kind string, id string, create func() T) T {
+
+	key := kind + " " + id
+	if e, ok := c.entities[key]; ok {
+		return e.(T)
+	}
+	e := create()
+	c.entities[key] = e
+
+	return e
+}
+
+//modelIdentity returns the kind and ID OrgCodec
+//records e with, if e belongs to the model
+func modelIdentity(e TimeTrackedEntity) (string, string, bool) {
+
+	switch v := e.(type) {
+	case *Organization:
+		return KindOrganization, v.ID, true
+	case *OrgUnit:
+		return KindUnit, v.ID, true
+	case *Position:
+		return KindPosition, v.ID, true
+	case *Person:
+		return KindPerson, v.ID, true
+	case *Assignment:
+		return KindAssignment, assignmentID(v), true
+	}
+
+	return "", "", false
+}
+
+//spanRecord builds the record of an entity of the model
+func spanRecord(kind string, id string, name string, span lifespan, b TemporalAttributeBearer, links map[string]string) EntityRecord {
+	return EntityRecord{
+		ID:      id,
+		Kind:    kind,
+		Name:    name,
+		From:    span.from,
+		Until:   span.until,
+		Links:   links,
+		History: HistoryOf(b),
+	}
+}
+
+//assignmentID identifies an assignment by its
+//person, position and start
+func assignmentID(a *Assignment) string {
+	return fmt.Sprintf("%s/%s/%s", a.Person.ID, a.Position.ID, canonicalTime(a.from))
+}
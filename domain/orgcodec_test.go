@@ -0,0 +1,226 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// orgModel builds a small model whose entities
+// carry attribute histories
+func orgModel(t *testing.T) (*Organization, []TimeTrackedEntity) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	org, err := NewOrganization("acme", "Acme", day(1), NilTime())
+	must(err)
+	sales, err := NewOrgUnit(org, nil, "sales", "Sales", day(1), NilTime())
+	must(err)
+	emea, err := NewOrgUnit(org, sales, "emea", "EMEA", day(2), day(20))
+	must(err)
+	head, err := NewPosition(emea, "head", "Head of EMEA", day(2), day(20))
+	must(err)
+	ann, err := NewPerson("ann", "Ann", day(1), NilTime())
+	must(err)
+	assignment, err := NewAssignment(ann, head, day(3), day(10))
+	must(err)
+
+	_, err = org.SetAttributeAt("country", "GR", day(1))
+	must(err)
+	_, err = emea.SetAttributeAt("budget", 100, day(2))
+	must(err)
+	_, err = emea.SetAttributeAt("budget", 150.5, day(5))
+	must(err)
+	must(emea.RemoveAttributeAt("budget", day(8)))
+	_, err = emea.SetAttributeAt("budget", 90, day(12))
+	must(err)
+	_, err = ann.SetAttributeAt("hired", time.Date(2019, 12, 15, 9, 0, 0, 0, time.UTC), day(1))
+	must(err)
+	_, err = ann.SetAttributeAt("skills", []interface{}{"go", "sql"}, day(4))
+	must(err)
+	_, err = assignment.SetAttributeAt("fte", 0.5, day(3))
+	must(err)
+
+	return org, []TimeTrackedEntity{org, sales, emea, head, ann, assignment}
+}
+
+// byKind indexes entities by their kind and ID
+func byKind(t *testing.T, entities []TimeTrackedEntity) map[string]TimeTrackedEntity {
+
+	codec := NewOrgCodec()
+	indexed := make(map[string]TimeTrackedEntity)
+	for _, e := range entities {
+		record, err := codec.Encode(e)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		indexed[record.Kind+" "+record.ID] = e
+	}
+	return indexed
+}
+
+func TestOrgCodecJSON(t *testing.T) {
+
+	_, entities := orgModel(t)
+	collection := EntityCollection{}
+	for _, e := range entities {
+		collection.AddEntity(e)
+	}
+
+	RegisterCodec[TimeTrackedEntity](NewOrgCodec())
+	t.Cleanup(UnregisterCodec[TimeTrackedEntity])
+	data, err := json.Marshal(&collection)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	codec := NewOrgCodec()
+	RegisterCodec[TimeTrackedEntity](codec)
+	decoded := EntityCollection{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if missing := codec.Missing(); len(missing) != 0 {
+		t.Errorf("expected every linked entity to be decoded, missing %v", missing)
+	}
+
+	original, restored := byKind(t, entities), byKind(t, decoded.Entities())
+	if len(restored) != len(original) {
+		t.Fatalf("expected %d entities, got %v", len(original), restored)
+	}
+	for key, e := range original {
+		r, ok := restored[key]
+		if !ok {
+			t.Errorf("%s: not decoded", key)
+			continue
+		}
+		if reflect.TypeOf(r) != reflect.TypeOf(e) || !r.ExistentFrom().Equal(e.ExistentFrom()) || !r.ValidUntil().Equal(e.ValidUntil()) {
+			t.Errorf("%s: expected %v, got %v", key, e, r)
+		}
+		if !reflect.DeepEqual(historiesOf(r.(TemporalAttributeBearer)), historiesOf(e.(TemporalAttributeBearer))) {
+			t.Errorf("%s: expected the history %v, got %v", key, HistoryOf(e.(TemporalAttributeBearer)), HistoryOf(r.(TemporalAttributeBearer)))
+		}
+	}
+
+	// the links between the entities are rebuilt
+	org := restored["organization acme"].(*Organization)
+	sales := restored["unit sales"].(*OrgUnit)
+	emea := restored["unit emea"].(*OrgUnit)
+	head := restored["position head"].(*Position)
+	ann := restored["person ann"].(*Person)
+	if org.Name != "Acme" || head.Title != "Head of EMEA" || ann.Name != "Ann" {
+		t.Errorf("unexpected names %q, %q, %q", org.Name, head.Title, ann.Name)
+	}
+	if roots := org.RootUnits(); len(roots) != 1 || roots[0] != sales || sales.Organization() != org {
+		t.Errorf("unexpected roots %v", roots)
+	}
+	if emea.Parent() != sales || len(sales.Children()) != 1 || emea.Organization() != org {
+		t.Errorf("unexpected hierarchy below %v", sales)
+	}
+	if head.Unit() != emea || len(emea.Positions()) != 1 {
+		t.Errorf("unexpected unit of %v", head)
+	}
+	for _, e := range decoded.Entities() {
+		if a, ok := e.(*Assignment); ok && (a.Person != ann || a.Position != head) {
+			t.Errorf("unexpected links of %v", a)
+		}
+	}
+
+	// times and the gaps of histories survive
+	hired, err := ann.GetAttributeAt("hired", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	if h, ok := hired.(time.Time); err != nil || !ok || !h.Equal(time.Date(2019, 12, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the time to come back as a time, got %T %v, %v", hired, hired, err)
+	}
+	if _, err := emea.GetAttributeAt("budget", time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC)); err != ErrAttributeNotFound {
+		t.Errorf("expected no budget during the gap, got %v", err)
+	}
+	if budget, err := emea.GetAttributeAt("budget", time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)); budget != 150.5 || err != nil {
+		t.Errorf("expected the budget in effect then, got %v, %v", budget, err)
+	}
+}
+
+func TestOrgCodecTyped(t *testing.T) {
+
+	_, entities := orgModel(t)
+	persons := TimeTrackedEntityCollection[*Person]{}
+	persons.AddEntity(entities[4].(*Person))
+
+	RegisterCodec(TypedCodec[*Person](NewOrgCodec()))
+	t.Cleanup(UnregisterCodec[*Person])
+	data, err := json.Marshal(&persons)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	decoded := TimeTrackedEntityCollection[*Person]{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if found := decoded.Entities(); len(found) != 1 || found[0].ID != "ann" || len(found[0].AttributeHistoryNames()) != 2 {
+		t.Errorf("unexpected persons %v", found)
+	}
+
+	// a unit can't be decoded to a person
+	record, err := NewOrgCodec().Encode(entities[1])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := TypedCodec[*Person](NewOrgCodec()).Decode(record); err == nil {
+		t.Error("expected a unit not to be decoded as a person")
+	}
+}
+
+func TestOrgCodecErrors(t *testing.T) {
+
+	_, entities := orgModel(t)
+	encoder := NewOrgCodec()
+	record, err := encoder.Encode(entities[5])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// an assignment decoded alone links to missing entities
+	codec := NewOrgCodec()
+	if _, err := codec.Decode(record); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if missing := codec.Missing(); strings.Join(missing, ",") != "person ann,position head" {
+		t.Errorf("unexpected missing entities %v", missing)
+	}
+	if _, err := codec.Decode(record); err == nil {
+		t.Error("expected a record decoded twice to be rejected")
+	}
+
+	overlapping := record
+	overlapping.ID = "other"
+	overlapping.History = []AttributeValue{
+		{Name: "fte", Value: 1, From: record.From},
+		{Name: "fte", Value: 0.5, From: record.From.AddDate(0, 0, 1)},
+	}
+	if _, err := NewOrgCodec().Decode(overlapping); !errors.Is(err, ErrHistoryOverlap) {
+		t.Errorf("expected ErrHistoryOverlap, got %v", err)
+	}
+
+	for _, broken := range []EntityRecord{
+		{ID: "x", Kind: "unknown", From: record.From},
+		{ID: "x", Kind: KindUnit, From: record.From},
+		{ID: "x", Kind: KindPerson},
+	} {
+		if _, err := NewOrgCodec().Decode(broken); err == nil {
+			t.Errorf("expected %+v to be rejected", broken)
+		}
+	}
+	if _, err := encoder.Encode(createMockTTEntity(record.From, NilTime())); err == nil {
+		t.Error("expected entities outside the model to be rejected")
+	}
+}
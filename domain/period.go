@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+//Period is a span of time, closed at its start and
+//open at its end, like a month or a fiscal year. It
+//saves callers from computing boundary timestamps
+//by hand when querying for a reporting period
+type Period struct {
+	start time.Time
+	end   time.Time
+	name  string
+}
+
+//NewPeriod returns the period [start, end). A zero
+//end stands for a period that has not ended
+func NewPeriod(start time.Time, end time.Time) Period {
+	return Period{start: start, end: end}
+}
+
+//Month returns the given month of year in loc
+func Month(year int, month time.Month, loc *time.Location) Period {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return Period{
+		start: start,
+		end:   start.AddDate(0, 1, 0),
+		name:  start.Format("2006-01"),
+	}
+}
+
+//Quarter returns the given calendar quarter (1 to 4) of year in loc
+func Quarter(year int, quarter int, loc *time.Location) Period {
+	start := time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, loc)
+	return Period{
+		start: start,
+		end:   start.AddDate(0, 3, 0),
+		name:  fmt.Sprintf("%dQ%d", start.Year(), (start.Month()-1)/3+1),
+	}
+}
+
+//Year returns the given calendar year in loc
+func Year(year int, loc *time.Location) Period {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+	return Period{
+		start: start,
+		end:   start.AddDate(1, 0, 0),
+		name:  start.Format("2006"),
+	}
+}
+
+//FiscalYear returns the fiscal year starting at startMonth.
+//Fiscal years are named after the calendar year they end in,
+//so with an October start FiscalYear(2023, time.October, loc)
+//spans from October 2022 to the end of September 2023
+func FiscalYear(year int, startMonth time.Month, loc *time.Location) Period {
+	return FiscalCalendar{StartMonth: startMonth, Location: loc}.Year(year)
+}
+
+//Start returns the first instant of the period
+func (p Period) Start() time.Time {
+	return p.start
+}
+
+//End returns the first instant after the
+//period, or the zero time if it has not ended
+func (p Period) End() time.Time {
+	return p.end
+}
+
+//Contains checks if pit falls within the period
+func (p Period) Contains(pit time.Time) bool {
+	return !pit.Before(p.start) && (p.end.IsZero() || pit.Before(p.end))
+}
+
+//String implementation of a period
+func (p Period) String() string {
+	if p.name != "" {
+		return p.name
+	}
+	return fmt.Sprintf("[%s -- %s)", p.start.Format(time.RFC3339), p.end.Format(time.RFC3339))
+}
+
+//ActiveDuring checks if the entity existed at any point of the
+//period. A period that has not ended takes in every entity still
+//existent at or after its start, as the method of collections does
+func ActiveDuring(e TimeTrackedEntity, p Period) bool {
+
+	if !p.end.IsZero() && !e.ExistentFrom().Before(p.end) {
+		return false
+	}
+
+	return e.ValidUntil().IsZero() || e.ValidUntil().After(p.start)
+}
+
+//ActiveDuring returns the entities of the collection that
+//existed at any point of the period, ordered by their start
+func (ts *TimeTrackedEntityCollection[T]) ActiveDuring(p Period) []T {
+
+	return ts.FindIntersecting(p.start, p.end)
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriods(t *testing.T) {
+
+	cases := []struct {
+		period Period
+		start  time.Time
+		end    time.Time
+		name   string
+	}{
+		{Month(2023, time.February, time.UTC),
+			time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), "2023-02"},
+		{Quarter(2023, 4, time.UTC),
+			time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2023Q4"},
+		{Year(2023, time.UTC),
+			time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2023"},
+		{FiscalYear(2023, time.October, time.UTC),
+			time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), "FY2023"},
+		{FiscalYear(2023, time.January, time.UTC),
+			time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "FY2023"},
+	}
+
+	for _, c := range cases {
+		if !c.period.Start().Equal(c.start) || !c.period.End().Equal(c.end) || c.period.String() != c.name {
+			t.Errorf("expected %s [%v, %v), got %s [%v, %v)",
+				c.name, c.start, c.end, c.period, c.period.Start(), c.period.End())
+		}
+		if !c.period.Contains(c.start) || c.period.Contains(c.end) {
+			t.Errorf("%s: containment of the boundaries is wrong", c.name)
+		}
+	}
+}
+
+func TestActiveDuring(t *testing.T) {
+
+	q1 := Quarter(2020, 1, time.UTC)
+
+	collection := EntityCollection{}
+	// ends exactly when the quarter starts
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	// open ended from before the quarter
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC),
+		NilTime()))
+	// within the quarter
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 2, 10, 0, 0, 0, 0, time.UTC)))
+	// starts exactly when the quarter ends
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+		NilTime()))
+
+	active := collection.ActiveDuring(q1)
+	if len(active) != 2 {
+		t.Fatalf("expected 2 active entities, got %d", len(active))
+	}
+	if !active[0].ValidUntil().IsZero() || active[1].ExistentFrom() != time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected entities %v", active)
+	}
+
+	// a period that has not ended takes in everything
+	// still existent at or after its start, for both forms
+	since := NewPeriod(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), NilTime())
+	if !since.Contains(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("expected an open period to contain later pits")
+	}
+	for _, p := range []Period{q1, since} {
+		found := make(map[TimeTrackedEntity]bool)
+		for _, e := range collection.ActiveDuring(p) {
+			found[e] = true
+		}
+		for _, e := range collection.Entities() {
+			if ActiveDuring(e, p) != found[e] {
+				t.Errorf("%v: the function and the method disagree on %v", p, e)
+			}
+		}
+	}
+	if open := collection.ActiveDuring(since); len(open) != 3 {
+		t.Errorf("expected 3 entities active since 2020, got %v", open)
+	}
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+//progressBatch is the number of items a bulk operation
+//processes between progress reports and cancellation checks
+const progressBatch = 1024
+
+//Progress reports how far a bulk operation has gone
+type Progress struct {
+	// items handled so far, failed ones included
+	Processed int
+	// items that could not be handled
+	Failed int
+	// items left to handle
+	Remaining int
+}
+
+//ProgressFunc is called by bulk operations every few
+//items and once more when they finish or are cancelled
+type ProgressFunc func(Progress)
+
+//report calls progress, if set
+func (progress ProgressFunc) report(processed int, failed int, total int) {
+	if progress != nil {
+		progress(Progress{Processed: processed, Failed: failed, Remaining: total - processed})
+	}
+}
+
+//AddEntities adds the entities to the collection in order,
+//reporting to progress, which may be nil. Entities that do not
+//end after they start are skipped and counted as failed, and
+//an error wrapping ErrEndBeforeStart reports them once all
+//the entities have been handled. If ctx is cancelled the
+//entities added so far remain in the collection and the
+//error of ctx is returned
+func (ts *TimeTrackedEntityCollection[T]) AddEntities(ctx context.Context, entities []T, progress ProgressFunc) error {
+
+	if err := ctx.Err(); err != nil {
+		progress.report(0, 0, len(entities))
+		return err
+	}
+
+	failed := 0
+	for i, e := range entities {
+		if i%progressBatch == 0 && i > 0 {
+			progress.report(i, failed, len(entities))
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if !e.ValidUntil().IsZero() && !e.ValidUntil().After(e.ExistentFrom()) {
+			failed++
+			continue
+		}
+		ts.AddEntity(e)
+	}
+	progress.report(len(entities), failed, len(entities))
+
+	if failed > 0 {
+		return fmt.Errorf("%d entities skipped: %w", failed, ErrEndBeforeStart)
+	}
+
+	return nil
+}
+
+//RebuildContext is Rebuild reporting to progress, which may
+//be nil, as the nodes are collected. If ctx is cancelled
+//before the tree is relinked the collection is left as it
+//was and the error of ctx is returned
+func (ts *TimeTrackedEntityCollection[T]) RebuildContext(ctx context.Context, progress ProgressFunc) error {
+
+	if err := ctx.Err(); err != nil {
+		progress.report(0, 0, ts.noOfNodes)
+		return err
+	}
+
+	var err error
+	nodes := make([]*intervalNode[T], 0, ts.noOfNodes)
+	walkNodes(ts.root, func(n *intervalNode[T]) bool {
+		nodes = append(nodes, n)
+		if len(nodes)%progressBatch == 0 {
+			progress.report(len(nodes), 0, ts.noOfNodes)
+			err = ctx.Err()
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ts.root = buildBalanced(nodes)
+	ts.height = optimalHeight(len(nodes))
+	// the cost of earlier queries says nothing of the new tree
+	atomic.StoreInt64(&ts.queries, 0)
+	atomic.StoreInt64(&ts.visits, 0)
+	progress.report(len(nodes), 0, len(nodes))
+
+	return nil
+}
+
+//walkNodes visits in order the nodes below n,
+//stopping as soon as visit returns false
+func walkNodes[T TimeTrackedEntity](n *intervalNode[T], visit func(*intervalNode[T]) bool) bool {
+
+	if n == nil {
+		return true
+	}
+
+	return walkNodes(n.left, visit) && visit(n) && walkNodes(n.right, visit)
+}
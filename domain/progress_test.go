@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func skewedEntities(n int) []TimeTrackedEntity {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	entities := make([]TimeTrackedEntity, n)
+	for i := range entities {
+		entities[i] = createMockTTEntity(start.Add(time.Duration(i)*time.Hour), NilTime())
+	}
+	return entities
+}
+
+func TestAddEntities(t *testing.T) {
+
+	entities := skewedEntities(3000)
+	start := entities[0].ExistentFrom()
+	entities[10] = createMockTTEntity(start, start.Add(-time.Hour))
+	// zero length intervals are rejected as well
+	entities[20] = createMockTTEntity(start, start)
+
+	collection := EntityCollection{}
+	var reports []Progress
+	err := collection.AddEntities(context.Background(), entities, func(p Progress) {
+		reports = append(reports, p)
+	})
+	if !errors.Is(err, ErrEndBeforeStart) {
+		t.Errorf("expected ErrEndBeforeStart, got %v", err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %v", reports)
+	}
+	if last := reports[len(reports)-1]; last != (Progress{Processed: 3000, Failed: 2, Remaining: 0}) {
+		t.Errorf("unexpected final report %+v", last)
+	}
+	if reports[0] != (Progress{Processed: 1024, Failed: 2, Remaining: 1976}) {
+		t.Errorf("unexpected first report %+v", reports[0])
+	}
+	if n := len(collection.Entities()); n != 2998 {
+		t.Errorf("expected 2998 entities, got %d", n)
+	}
+}
+
+func TestAddEntitiesCancelledBefore(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	collection := EntityCollection{}
+	if err := collection.AddEntities(ctx, skewedEntities(10), nil); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if n := len(collection.Entities()); n != 0 {
+		t.Errorf("expected no entities to be added, got %d", n)
+	}
+}
+
+func TestAddEntitiesCancelled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	collection := EntityCollection{}
+	err := collection.AddEntities(ctx, skewedEntities(5000), func(p Progress) {
+		cancel()
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if n := len(collection.Entities()); n != 1024 {
+		t.Errorf("expected the first batch to be added, got %d entities", n)
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+}
+
+func TestRebuildContext(t *testing.T) {
+
+	collection := EntityCollection{}
+	for _, e := range skewedEntities(2500) {
+		collection.AddEntity(e)
+	}
+	height := collection.Stats().Height
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := collection.RebuildContext(ctx, nil); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if collection.Stats().Height != height {
+		t.Error("expected a cancelled rebuild to leave the tree untouched")
+	}
+
+	var last Progress
+	if err := collection.RebuildContext(context.Background(), func(p Progress) { last = p }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if last != (Progress{Processed: 2500}) {
+		t.Errorf("unexpected final report %+v", last)
+	}
+	if stats := collection.Stats(); stats.Height != stats.OptimalHeight {
+		t.Errorf("expected an optimal tree, got %+v", stats)
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+}
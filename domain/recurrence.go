@@ -0,0 +1,314 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//maxEmptyPeriods bounds how many consecutive frequency
+//periods may produce no occurrence before the expansion
+//gives up, for rules that can never match (BYMONTHDAY=31
+//with FREQ=MONTHLY and INTERVAL=12 starting in February)
+const maxEmptyPeriods = 1000
+
+//Frequency is the FREQ part of a recurrence rule
+type Frequency int
+
+const (
+	// Daily recurrence
+	Daily Frequency = iota
+	// Weekly recurrence
+	Weekly
+	// Monthly recurrence
+	Monthly
+	// Yearly recurrence
+	Yearly
+)
+
+//RRule is a recurrence rule, as defined by RFC 5545. Only
+//a subset is supported: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY),
+//INTERVAL, COUNT, UNTIL, BYDAY without ordinals for weekly
+//rules and BYMONTHDAY for monthly ones, ParseRRule rejects
+//them for other frequencies. Weeks start on Monday
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+//weekdays maps the two letter RFC 5545 day names
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+//ParseRRule parses the value of an RRULE property, like
+//"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10"
+func ParseRRule(value string) (RRule, error) {
+
+	rule := RRule{Interval: 1}
+	hasFreq := false
+
+	for _, part := range strings.Split(strings.TrimPrefix(value, "RRULE:"), ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return RRule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			hasFreq = true
+			switch strings.ToUpper(kv[1]) {
+			case "DAILY":
+				rule.Freq = Daily
+			case "WEEKLY":
+				rule.Freq = Weekly
+			case "MONTHLY":
+				rule.Freq = Monthly
+			case "YEARLY":
+				rule.Freq = Yearly
+			default:
+				return RRule{}, fmt.Errorf("unsupported frequency %q", kv[1])
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(kv[1])
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("interval must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(kv[1])
+			if err == nil && rule.Count < 1 {
+				err = errors.New("count must be positive")
+			}
+		case "UNTIL":
+			rule.Until, err = parseRRuleTime(kv[1])
+		case "BYDAY":
+			for _, day := range strings.Split(kv[1], ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return RRule{}, fmt.Errorf("unsupported BYDAY value %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(kv[1], ",") {
+				n, convErr := strconv.Atoi(day)
+				if convErr != nil || n == 0 || n < -31 || n > 31 {
+					return RRule{}, fmt.Errorf("invalid BYMONTHDAY value %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return RRule{}, fmt.Errorf("unsupported rule part %q", kv[0])
+		}
+
+		if err != nil {
+			return RRule{}, fmt.Errorf("invalid %s: %v", kv[0], err)
+		}
+	}
+
+	if !hasFreq {
+		return RRule{}, errors.New("rule has no FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return RRule{}, errors.New("COUNT and UNTIL are mutually exclusive")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return RRule{}, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return RRule{}, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	return rule, nil
+}
+
+//parseRRuleTime parses the date or UTC date-time forms of UNTIL
+func parseRRuleTime(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	return time.Parse("20060102", value)
+}
+
+//Recurrence expands a rule into time tracked occurrences,
+//each lasting Duration from the pit it recurs at
+type Recurrence struct {
+	Rule     RRule
+	Start    time.Time
+	Duration time.Duration
+}
+
+//Occurrence is a single concrete occurrence of a Recurrence.
+//It implements TimeTrackedEntity
+type Occurrence struct {
+	// the position of the occurrence in the
+	// recurrence, starting from 0
+	Index int
+	start time.Time
+	end   time.Time
+}
+
+//IsExistentAt implementation of TimeTrackedEntity
+func (o Occurrence) IsExistentAt(pit time.Time) bool {
+	return !pit.Before(o.start) && pit.Before(o.end)
+}
+
+//ExistentFrom implementation of TimeTrackedEntity
+func (o Occurrence) ExistentFrom() time.Time {
+	return o.start
+}
+
+//ValidUntil implementation of TimeTrackedEntity
+func (o Occurrence) ValidUntil() time.Time {
+	return o.end
+}
+
+//ActiveDuration implementation of TimeTrackedEntity
+func (o Occurrence) ActiveDuration() time.Duration {
+	return o.end.Sub(o.start)
+}
+
+//String implementation of an occurrence
+func (o Occurrence) String() string {
+	return fmt.Sprintf("#%d [%s -- %s]", o.Index,
+		o.start.Format("2006-01-02 15:04:05"), o.end.Format("2006-01-02 15:04:05"))
+}
+
+//Iterate returns an iterator that lazily produces the
+//occurrences of the recurrence in chronological order
+func (r Recurrence) Iterate() *OccurrenceIterator {
+	return &OccurrenceIterator{recurrence: r}
+}
+
+//Between returns the occurrences that exist at some point of
+//[from, to). The window bounds the expansion, so rules without
+//COUNT or UNTIL can be expanded safely
+func (r Recurrence) Between(from time.Time, to time.Time) []Occurrence {
+
+	occurrences := make([]Occurrence, 0)
+	it := r.Iterate()
+	for o, ok := it.Next(); ok && o.start.Before(to); o, ok = it.Next() {
+		if o.end.After(from) {
+			occurrences = append(occurrences, o)
+		}
+	}
+
+	return occurrences
+}
+
+//During returns the occurrences that exist at some point of p
+func (r Recurrence) During(p Period) []Occurrence {
+	return r.Between(p.Start(), p.End())
+}
+
+//OccurrenceIterator produces the occurrences of a recurrence
+type OccurrenceIterator struct {
+	recurrence Recurrence
+	// the frequency period expanded next
+	period  int
+	pending []time.Time
+	emitted int
+	done    bool
+}
+
+//Next returns the next occurrence, or false when the
+//recurrence has no more occurrences
+func (it *OccurrenceIterator) Next() (Occurrence, bool) {
+
+	rule := it.recurrence.Rule
+	for empty := 0; len(it.pending) == 0; empty++ {
+		if it.done || empty > maxEmptyPeriods {
+			it.done = true
+			return Occurrence{}, false
+		}
+		it.pending = it.recurrence.candidates(it.period)
+		it.period++
+	}
+
+	pit := it.pending[0]
+	it.pending = it.pending[1:]
+
+	if (!rule.Until.IsZero() && pit.After(rule.Until)) || (rule.Count > 0 && it.emitted >= rule.Count) {
+		it.done = true
+		it.pending = nil
+		return Occurrence{}, false
+	}
+
+	o := Occurrence{Index: it.emitted, start: pit, end: pit.Add(it.recurrence.Duration)}
+	it.emitted++
+
+	return o, true
+}
+
+//candidates returns the sorted pits the rule produces within
+//the n-th frequency period, leaving out those before Start
+func (r Recurrence) candidates(n int) []time.Time {
+
+	rule := r.Rule
+	interval := rule.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	step := n * interval
+
+	s := r.Start
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, s.Hour(), s.Minute(), s.Second(), s.Nanosecond(), s.Location())
+	}
+
+	var pits []time.Time
+	switch rule.Freq {
+	case Daily:
+		pits = []time.Time{s.AddDate(0, 0, step)}
+	case Weekly:
+		monday := s.AddDate(0, 0, -((int(s.Weekday())+6)%7)+7*step)
+		days := rule.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{s.Weekday()}
+		}
+		for _, d := range days {
+			pits = append(pits, monday.AddDate(0, 0, (int(d)+6)%7))
+		}
+	case Monthly:
+		first := time.Date(s.Year(), s.Month()+time.Month(step), 1, 0, 0, 0, 0, s.Location())
+		daysInMonth := first.AddDate(0, 1, -1).Day()
+		days := rule.ByMonthDay
+		if len(days) == 0 {
+			days = []int{s.Day()}
+		}
+		for _, d := range days {
+			if d < 0 {
+				d = daysInMonth + d + 1
+			}
+			if d >= 1 && d <= daysInMonth {
+				pits = append(pits, at(first.Year(), first.Month(), d))
+			}
+		}
+	case Yearly:
+		pit := at(s.Year()+step, s.Month(), s.Day())
+		// Feb 29th doesn't occur in non leap years
+		if pit.Day() == s.Day() {
+			pits = []time.Time{pit}
+		}
+	}
+
+	sort.Slice(pits, func(i, j int) bool { return pits[i].Before(pits[j]) })
+
+	valid := pits[:0]
+	for i, pit := range pits {
+		if !pit.Before(s) && (i == 0 || !pit.Equal(pits[i-1])) {
+			valid = append(valid, pit)
+		}
+	}
+
+	return valid
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+
+	rule, err := ParseRRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.Freq != Weekly || rule.Interval != 2 || rule.Count != 10 || len(rule.ByDay) != 2 || rule.ByDay[1] != time.Thursday {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	rule, err = ParseRRule("FREQ=MONTHLY;BYMONTHDAY=1,-1;UNTIL=20201231")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.Until != time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC) || rule.ByMonthDay[1] != -1 {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	for _, invalid := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20200101", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYDAY=MO,WE", "FREQ=MONTHLY;BYDAY=MO", "FREQ=YEARLY;BYMONTHDAY=30", "FREQ=WEEKLY;BYMONTHDAY=1"} {
+		if _, err := ParseRRule(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestRecurrenceWeekly(t *testing.T) {
+
+	rule, _ := ParseRRule("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5")
+
+	// a Thursday
+	start := time.Date(2020, 1, 2, 9, 0, 0, 0, time.UTC)
+	r := Recurrence{Rule: rule, Start: start, Duration: 2 * time.Hour}
+
+	occurrences := r.Between(start, start.AddDate(1, 0, 0))
+	expected := []time.Time{
+		time.Date(2020, 1, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 13, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 16, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 27, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 30, 9, 0, 0, 0, time.UTC),
+	}
+	if len(occurrences) != len(expected) {
+		t.Fatalf("expected %d occurrences, got %v", len(expected), occurrences)
+	}
+	for i, o := range occurrences {
+		if o.ExistentFrom() != expected[i] || o.ActiveDuration() != 2*time.Hour || o.Index != i {
+			t.Errorf("occurrence %d: unexpected %v", i, o)
+		}
+	}
+}
+
+func TestRecurrenceMonthlyWindow(t *testing.T) {
+
+	rule, _ := ParseRRule("FREQ=MONTHLY;BYMONTHDAY=31,-1")
+	start := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	r := Recurrence{Rule: rule, Start: start, Duration: 24 * time.Hour}
+
+	// unbounded rule, bounded by the window
+	occurrences := r.During(Quarter(2020, 1, time.UTC))
+	expected := []time.Time{
+		time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+	if len(occurrences) != len(expected) {
+		t.Fatalf("expected %d occurrences, got %v", len(expected), occurrences)
+	}
+	for i, o := range occurrences {
+		if o.ExistentFrom() != expected[i] {
+			t.Errorf("occurrence %d: expected %v, got %v", i, expected[i], o.ExistentFrom())
+		}
+	}
+
+	// an occurrence started before the window but still existent in it
+	if o := r.Between(expected[1].Add(time.Hour), expected[2]); len(o) != 1 {
+		t.Errorf("expected the running occurrence, got %v", o)
+	}
+}
+
+func TestRecurrenceUntilAndLeapYears(t *testing.T) {
+
+	rule, _ := ParseRRule("FREQ=YEARLY;UNTIL=20290101")
+	start := time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)
+	r := Recurrence{Rule: rule, Start: start, Duration: time.Hour}
+
+	it := r.Iterate()
+	var got []time.Time
+	for o, ok := it.Next(); ok; o, ok = it.Next() {
+		got = append(got, o.ExistentFrom())
+	}
+	if len(got) != 3 || got[1].Year() != 2024 || got[2].Year() != 2028 {
+		t.Errorf("expected the leap years up to 2028, got %v", got)
+	}
+
+	// February never has a 31st
+	never, _ := ParseRRule("FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31")
+	r = Recurrence{Rule: never, Start: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), Duration: time.Hour}
+	if o, ok := r.Iterate().Next(); ok {
+		t.Errorf("expected no occurrences, got %v", o)
+	}
+}
@@ -0,0 +1,224 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+)
+
+//ErrSchemaViolation is wrapped by every error reported
+//when attributes do not conform to an AttributeSchema
+var ErrSchemaViolation = errors.New("attribute schema violated")
+
+//AttributeType is the type of value an attribute holds
+type AttributeType int
+
+const (
+	// AnyType accepts values of any type
+	AnyType AttributeType = iota
+	// StringType accepts strings
+	StringType
+	// IntType accepts values of any integer type
+	// that fit in an int
+	IntType
+	// FloatType accepts float32 and float64 values
+	FloatType
+	// BoolType accepts booleans
+	BoolType
+	// TimeType accepts time.Time values
+	TimeType
+)
+
+//String implementation of an attribute type
+func (t AttributeType) String() string {
+	switch t {
+	case StringType:
+		return "string"
+	case IntType:
+		return "int"
+	case FloatType:
+		return "float"
+	case BoolType:
+		return "bool"
+	case TimeType:
+		return "time"
+	}
+	return "any"
+}
+
+//AttributeValidator checks the value of an attribute,
+//once it is known to be of the declared type
+type AttributeValidator func(value interface{}) error
+
+//MatchesPattern is an AttributeValidator accepting
+//strings matched by re
+func MatchesPattern(re *regexp.Regexp) AttributeValidator {
+	return func(value interface{}) error {
+		if s, ok := value.(string); !ok || !re.MatchString(s) {
+			return fmt.Errorf("%v does not match %s", value, re)
+		}
+		return nil
+	}
+}
+
+//InRange is an AttributeValidator accepting
+//integers between min and max, both included
+func InRange(min int, max int) AttributeValidator {
+	return func(value interface{}) error {
+		n, err := toInt("", value)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("%v is not in [%d, %d]", value, min, max)
+		}
+		return nil
+	}
+}
+
+//OneOf is an AttributeValidator accepting only the given
+//values. Numbers are compared by value whatever their kind,
+//so int64(3) matches 3, and other values are compared deeply
+func OneOf(values ...interface{}) AttributeValidator {
+	return func(value interface{}) error {
+		for _, allowed := range values {
+			if sameValue(value, allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v is not one of %v", value, values)
+	}
+}
+
+//sameValue checks if a and b are the same number,
+//or are otherwise deeply equal
+func sameValue(a interface{}, b interface{}) bool {
+
+	if x, err := toInt("", a); err == nil {
+		if y, err := toInt("", b); err == nil {
+			return x == y
+		}
+	}
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return x == y
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+//AttributeDefinition declares a single attribute of a schema
+type AttributeDefinition struct {
+	// the name of the attribute
+	Name string
+	// the type of its values
+	Type AttributeType
+	// whether every entity must carry it
+	Required bool
+	// further checks on the value, run in order
+	// after the type has been checked
+	Validators []AttributeValidator
+}
+
+//AttributeSchema constrains the attributes an entity type may
+//carry. Attributes that are not declared are rejected unless
+//AllowUndeclared is set
+type AttributeSchema struct {
+	Attributes      []AttributeDefinition
+	AllowUndeclared bool
+}
+
+//Validate checks every attribute of b against the schema and
+//verifies that the required ones are present. All the
+//violations found are reported, each one wrapping
+//ErrSchemaViolation. nil is returned if b conforms
+func (s *AttributeSchema) Validate(b AttributeBearer) error {
+
+	var errs []error
+	for _, def := range s.Attributes {
+		if def.Required && !b.HasAttribute(def.Name) {
+			errs = append(errs, fmt.Errorf("%s is required: %w", def.Name, ErrSchemaViolation))
+		}
+	}
+
+	names := b.GetAttributeNames()
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := b.GetAttribute(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := s.ValidateAttribute(name, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+//ValidateAttribute checks a single value against the
+//definition of the attribute, without considering the
+//other attributes of the entity
+func (s *AttributeSchema) ValidateAttribute(attrName string, value interface{}) error {
+
+	def, ok := s.definition(attrName)
+	if !ok {
+		if s.AllowUndeclared {
+			return nil
+		}
+		return fmt.Errorf("%s is not declared: %w", attrName, ErrSchemaViolation)
+	}
+
+	if !def.Type.accepts(value) {
+		return fmt.Errorf("%s should be %s, got %T: %w", attrName, def.Type, value, ErrSchemaViolation)
+	}
+
+	for _, validator := range def.Validators {
+		if err := validator(value); err != nil {
+			return fmt.Errorf("%s: %v: %w", attrName, err, ErrSchemaViolation)
+		}
+	}
+
+	return nil
+}
+
+//definition looks up the definition of an attribute
+func (s *AttributeSchema) definition(attrName string) (AttributeDefinition, bool) {
+
+	for _, def := range s.Attributes {
+		if def.Name == attrName {
+			return def, true
+		}
+	}
+
+	return AttributeDefinition{}, false
+}
+
+//accepts checks if value is of type t
+func (t AttributeType) accepts(value interface{}) bool {
+
+	switch t {
+	case StringType:
+		_, ok := value.(string)
+		return ok
+	case IntType:
+		_, err := toInt("", value)
+		return err == nil
+	case FloatType:
+		switch value.(type) {
+		case float32, float64:
+			return true
+		}
+		return false
+	case BoolType:
+		_, ok := value.(bool)
+		return ok
+	case TimeType:
+		_, ok := value.(time.Time)
+		return ok
+	}
+
+	return true
+}
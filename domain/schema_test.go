@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func positionSchema() *AttributeSchema {
+	return &AttributeSchema{
+		Attributes: []AttributeDefinition{
+			{Name: "grade", Type: IntType, Required: true, Validators: []AttributeValidator{InRange(1, 12)}},
+			{Name: "costCenter", Type: StringType, Validators: []AttributeValidator{MatchesPattern(regexp.MustCompile(`^CC-\d{4}$`))}},
+			{Name: "since", Type: TimeType},
+		},
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+
+	schema := positionSchema()
+
+	valid := &BaseAttributeBearer{}
+	valid.SetAttribute("grade", int64(7))
+	valid.SetAttribute("costCenter", "CC-0042")
+	valid.SetAttribute("since", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := schema.Validate(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	invalid := &BaseAttributeBearer{}
+	invalid.SetAttribute("costCenter", "42")
+	invalid.SetAttribute("since", "2020-01-01")
+	invalid.SetAttribute("office", "Athens")
+	err := schema.Validate(invalid)
+	if !errors.Is(err, ErrSchemaViolation) {
+		t.Fatalf("expected ErrSchemaViolation, got %v", err)
+	}
+	for _, expected := range []string{"grade is required", "costCenter", "since should be time", "office is not declared"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to be reported in %v", expected, err)
+		}
+	}
+
+	schema.AllowUndeclared = true
+	if err := schema.ValidateAttribute("office", "Athens"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := schema.ValidateAttribute("grade", 13); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("expected an out of range grade to be rejected, got %v", err)
+	}
+}
+
+func TestSetAttributeChecked(t *testing.T) {
+
+	b := &BaseAttributeBearer{}
+	if _, err := b.SetAttributeChecked("grade", "high"); err != nil {
+		t.Errorf("expected no validation without a schema, got %v", err)
+	}
+
+	b.SetSchema(positionSchema())
+	if _, err := b.SetAttributeChecked("grade", "high"); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("expected ErrSchemaViolation, got %v", err)
+	}
+	if previous, err := b.SetAttributeChecked("grade", 3); err != nil || previous != "high" {
+		t.Errorf("unexpected result %v, %v", previous, err)
+	}
+	if _, err := b.SetAttributeChecked("costCenter", "CC-1"); err == nil {
+		t.Error("expected a malformed cost center to be rejected")
+	}
+	if b.HasAttribute("costCenter") {
+		t.Error("expected a rejected value not to be set")
+	}
+}
+
+func TestOneOf(t *testing.T) {
+
+	validator := OneOf(3, "three", []string{"a", "b"}, map[string]int{"x": 1})
+	for _, value := range []interface{}{3, int64(3), uint8(3), 3.0, "three", []string{"a", "b"}, map[string]int{"x": 1}} {
+		if err := validator(value); err != nil {
+			t.Errorf("%v: unexpected error %v", value, err)
+		}
+	}
+	for _, value := range []interface{}{4, "3", []string{"a"}, map[string]int{"x": 2}, nil} {
+		if err := validator(value); err == nil {
+			t.Errorf("%v: expected an error", value)
+		}
+	}
+}
+
+// unreadableBearer fails to return one of the attributes it lists
+type unreadableBearer struct {
+	BaseAttributeBearer
+}
+
+func (b *unreadableBearer) GetAttribute(attrName string) (interface{}, error) {
+	if attrName == "broken" {
+		return nil, errors.New("cannot read broken")
+	}
+	return b.BaseAttributeBearer.GetAttribute(attrName)
+}
+
+func TestValidateKeepsErrors(t *testing.T) {
+
+	b := &unreadableBearer{}
+	b.SetAttribute("broken", 1)
+	b.SetAttribute("office", "Athens")
+
+	err := positionSchema().Validate(b)
+	for _, expected := range []string{"grade is required", "cannot read broken", "office is not declared"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to be reported in %v", expected, err)
+		}
+	}
+}
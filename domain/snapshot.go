@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"time"
+)
+
+//OrgSnapshot is a read only view of an organization as it
+//was at a given pit: the units and positions existent then,
+//linked in their hierarchy, and the people holding them
+type OrgSnapshot struct {
+	// the pit the snapshot was taken at
+	At           time.Time
+	Organization *Organization
+
+	roots []*SnapshotUnit
+	units map[string]*SnapshotUnit
+}
+
+//SnapshotUnit is a unit within an OrgSnapshot
+type SnapshotUnit struct {
+	Unit *OrgUnit
+
+	parent    *SnapshotUnit
+	children  []*SnapshotUnit
+	positions []*SnapshotPosition
+}
+
+//SnapshotPosition is a position within an OrgSnapshot
+type SnapshotPosition struct {
+	Position *Position
+	// the person holding the position at the
+	// pit of the snapshot, nil if it was vacant
+	Holder *Person
+	// the assignment of the holder,
+	// nil if the position was vacant
+	Assignment *Assignment
+
+	unit *SnapshotUnit
+}
+
+//OrgSnapshot reconstructs the organization as it was at the
+//given pit. Units and positions not existent then are left
+//out, units are placed below the parents they had then, see
+//OrgUnit.ParentAt, and the holders of positions are looked
+//up in assignments, which may be nil
+func (o *Organization) OrgSnapshot(at time.Time, assignments *AssignmentCollection) *OrgSnapshot {
+
+	snapshot := &OrgSnapshot{At: at, Organization: o, units: make(map[string]*SnapshotUnit)}
+	var roots []*OrgUnit
+	below := make(map[*OrgUnit][]*OrgUnit)
+	for _, u := range o.units() {
+		if parent := u.ParentAt(at); parent != nil {
+			below[parent] = append(below[parent], u)
+		} else {
+			roots = append(roots, u)
+		}
+	}
+	for _, root := range roots {
+		snapshot.roots = snapshot.addUnit(root, nil, snapshot.roots, below, assignments)
+	}
+
+	return snapshot
+}
+
+//addUnit adds u, if existent, and the units below it, as
+//listed in below, under parent, which is nil at the top of the
+//hierarchy, and returns siblings with u appended. Units exist
+//within their parent, so the descendants of a unit that is
+//not existent are not existent either
+func (s *OrgSnapshot) addUnit(u *OrgUnit, parent *SnapshotUnit, siblings []*SnapshotUnit, below map[*OrgUnit][]*OrgUnit, assignments *AssignmentCollection) []*SnapshotUnit {
+
+	if !u.IsExistentAt(s.At) {
+		return siblings
+	}
+
+	unit := &SnapshotUnit{Unit: u, parent: parent}
+	s.units[u.ID] = unit
+	for _, p := range u.positions {
+		if !p.IsExistentAt(s.At) {
+			continue
+		}
+		position := &SnapshotPosition{Position: p, unit: unit}
+		if assignments != nil {
+			if a := assignments.AssignmentAt(p, s.At); a != nil {
+				position.Holder, position.Assignment = a.Person, a
+			}
+		}
+		unit.positions = append(unit.positions, position)
+	}
+	for _, child := range below[u] {
+		unit.children = s.addUnit(child, unit, unit.children, below, assignments)
+	}
+
+	return append(siblings, unit)
+}
+
+//Roots returns the units at the top of the hierarchy
+func (s *OrgSnapshot) Roots() []*SnapshotUnit {
+	return append([]*SnapshotUnit(nil), s.roots...)
+}
+
+//Unit returns the unit with the given id,
+//or nil if it was not existent
+func (s *OrgSnapshot) Unit(id string) *SnapshotUnit {
+	return s.units[id]
+}
+
+//Walk visits every unit of the snapshot in pre order,
+//stopping as soon as visit returns false
+func (s *OrgSnapshot) Walk(visit func(u *SnapshotUnit) bool) {
+	for _, root := range s.roots {
+		if !root.walk(visit) {
+			return
+		}
+	}
+}
+
+//walk visits u and its descendants in pre order
+func (u *SnapshotUnit) walk(visit func(u *SnapshotUnit) bool) bool {
+
+	if !visit(u) {
+		return false
+	}
+	for _, child := range u.children {
+		if !child.walk(visit) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Parent returns the unit directly above u,
+//or nil at the top of the hierarchy
+func (u *SnapshotUnit) Parent() *SnapshotUnit {
+	return u.parent
+}
+
+//Children returns the units directly below u
+func (u *SnapshotUnit) Children() []*SnapshotUnit {
+	return append([]*SnapshotUnit(nil), u.children...)
+}
+
+//Positions returns the positions of u
+func (u *SnapshotUnit) Positions() []*SnapshotPosition {
+	return append([]*SnapshotPosition(nil), u.positions...)
+}
+
+//Holders returns the people holding the positions of u
+func (u *SnapshotUnit) Holders() []*Person {
+
+	var holders []*Person
+	for _, p := range u.positions {
+		if p.Holder != nil {
+			holders = append(holders, p.Holder)
+		}
+	}
+
+	return holders
+}
+
+//Unit returns the unit the position belongs to
+func (p *SnapshotPosition) Unit() *SnapshotUnit {
+	return p.unit
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOrgSnapshot(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	org, _ := NewOrganization("acme", "Acme", day(1), NilTime())
+	sales, _ := NewOrgUnit(org, nil, "sales", "Sales", day(1), NilTime())
+	emea, _ := NewOrgUnit(org, sales, "emea", "EMEA", day(1), NilTime())
+	apac, _ := NewOrgUnit(org, sales, "apac", "APAC", day(10), NilTime())
+	greece, _ := NewOrgUnit(org, emea, "greece", "Greece", day(1), NilTime())
+	head, _ := NewPosition(sales, "head", "Head of Sales", day(1), NilTime())
+	NewPosition(sales, "deputy", "Deputy Head of Sales", day(5), NilTime())
+	ann, _ := NewPerson("ann", "Ann", day(1), NilTime())
+
+	var assignments AssignmentCollection
+	a, _ := NewAssignment(ann, head, day(2), NilTime())
+	assignments.AddAssignment(a)
+
+	before := org.OrgSnapshot(day(1), &assignments)
+	if roots := before.Roots(); len(roots) != 1 || roots[0].Unit != sales {
+		t.Fatalf("unexpected roots %v", roots)
+	}
+	root := before.Unit("sales")
+	if children := root.Children(); len(children) != 1 || children[0].Unit != emea || children[0].Parent() != root {
+		t.Errorf("expected only EMEA below sales, got %v", children)
+	}
+	if before.Unit("apac") != nil {
+		t.Error("expected APAC not to exist yet")
+	}
+	if positions := root.Positions(); len(positions) != 1 || positions[0].Holder != nil || positions[0].Unit() != root {
+		t.Errorf("expected a single vacant position, got %v", positions)
+	}
+
+	after := org.OrgSnapshot(day(12), &assignments)
+	root = after.Unit("sales")
+	if len(root.Children()) != 2 || len(root.Positions()) != 2 {
+		t.Errorf("expected two units and two positions below sales, got %v and %v", root.Children(), root.Positions())
+	}
+	if holders := root.Holders(); len(holders) != 1 || holders[0] != ann {
+		t.Errorf("expected Ann to hold a position, got %v", holders)
+	}
+
+	// units ending leave the snapshots
+	// taken after they ended
+	if err := emea.End(day(15)); err != ErrOutsideParent {
+		t.Errorf("expected EMEA to outlive Greece, got %v", err)
+	}
+	greece.End(day(15))
+	emea.End(day(15))
+	var visited []string
+	org.OrgSnapshot(day(20), nil).Walk(func(u *SnapshotUnit) bool {
+		visited = append(visited, u.Unit.ID)
+		return true
+	})
+	if len(visited) != 2 || visited[0] != "sales" || visited[1] != apac.ID {
+		t.Errorf("unexpected walk %v", visited)
+	}
+}
+
+func TestOrgSnapshotMovedUnit(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	org, _ := NewOrganization("acme", "Acme", day(1), NilTime())
+	sales, _ := NewOrgUnit(org, nil, "sales", "Sales", day(1), NilTime())
+	support, _ := NewOrgUnit(org, nil, "support", "Support", day(1), NilTime())
+	emea, _ := NewOrgUnit(org, sales, "emea", "EMEA", day(1), NilTime())
+
+	if err := emea.MoveAt(support, day(10)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if emea.Parent() != support || emea.ParentAt(day(5)) != sales || emea.ParentAt(day(10)) != support {
+		t.Errorf("unexpected parents %v, %v", emea.ParentAt(day(5)), emea.ParentAt(day(10)))
+	}
+	if before := org.OrgSnapshot(day(5), nil).Unit("emea"); before.Parent().Unit != sales {
+		t.Errorf("expected EMEA below sales before the move, got %v", before.Parent().Unit)
+	}
+	if after := org.OrgSnapshot(day(12), nil).Unit("emea"); after.Parent().Unit != support {
+		t.Errorf("expected EMEA below support after the move, got %v", after.Parent().Unit)
+	}
+
+	// the former parent can't end while it still held the unit
+	if err := sales.End(day(8)); err != ErrOutsideParent {
+		t.Errorf("expected ErrOutsideParent, got %v", err)
+	}
+	if err := sales.End(day(10)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// moves can't form a cycle at any pit
+	if err := support.MoveAt(emea, day(5)); err != ErrUnitCycle {
+		t.Errorf("expected ErrUnitCycle, got %v", err)
+	}
+	if err := support.SetParent(emea); err != ErrUnitCycle {
+		t.Errorf("expected ErrUnitCycle, got %v", err)
+	}
+
+	// the history survives the codec
+	RegisterCodec[TimeTrackedEntity](NewOrgCodec())
+	t.Cleanup(UnregisterCodec[TimeTrackedEntity])
+	collection := EntityCollection{}
+	for _, e := range []TimeTrackedEntity{org, sales, support, emea} {
+		collection.AddEntity(e)
+	}
+	data, err := json.Marshal(&collection)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	decoded := EntityCollection{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	restored := byKind(t, decoded.Entities())
+	restoredOrg := restored["organization acme"].(*Organization)
+	if u := restoredOrg.OrgSnapshot(day(5), nil).Unit("emea"); u.Parent().Unit != restored["unit sales"] {
+		t.Errorf("expected the decoded EMEA below sales before the move, got %v", u.Parent().Unit)
+	}
+	if u := restoredOrg.OrgSnapshot(day(12), nil).Unit("emea"); u.Parent().Unit != restored["unit support"] {
+		t.Errorf("expected the decoded EMEA below support after the move, got %v", u.Parent().Unit)
+	}
+
+	// setting the parent rewrites the whole history
+	if err := emea.SetParent(nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if emea.ParentAt(day(5)) != nil || org.OrgSnapshot(day(5), nil).Unit("emea").Parent() != nil {
+		t.Error("expected EMEA at the top of the hierarchy at every pit")
+	}
+}
@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+//ChangeKind is the kind of an OrgChange
+type ChangeKind int
+
+const (
+	// UnitCreated reports a unit existent only in the later snapshot
+	UnitCreated ChangeKind = iota
+	// UnitClosed reports a unit existent only in the earlier snapshot
+	UnitClosed
+	// PositionAdded reports a position existent only in the later snapshot
+	PositionAdded
+	// PositionRemoved reports a position existent only in the earlier snapshot
+	PositionRemoved
+	// AssignmentStarted reports an assignment in effect only in the later snapshot
+	AssignmentStarted
+	// AssignmentEnded reports an assignment in effect only in the earlier snapshot
+	AssignmentEnded
+	// AttributeChanged reports an attribute whose value differs
+	AttributeChanged
+)
+
+//String implementation of a change kind
+func (k ChangeKind) String() string {
+	switch k {
+	case UnitCreated:
+		return "unit created"
+	case UnitClosed:
+		return "unit closed"
+	case PositionAdded:
+		return "position added"
+	case PositionRemoved:
+		return "position removed"
+	case AssignmentStarted:
+		return "assignment started"
+	case AssignmentEnded:
+		return "assignment ended"
+	}
+	return "attribute changed"
+}
+
+//OrgChange is a single difference between two snapshots
+type OrgChange struct {
+	Kind ChangeKind
+	// the unit, position, assignment, person or
+	// organization the change refers to
+	Entity TimeTrackedEntity
+	// the attribute that changed,
+	// set for AttributeChanged only
+	Attribute string
+	// the values of the attribute in each snapshot, nil
+	// where it held no value, set for AttributeChanged only
+	Before, After interface{}
+}
+
+//String implementation of a change
+func (c OrgChange) String() string {
+	if c.Kind == AttributeChanged {
+		return fmt.Sprintf("%v: %s changed (%v -> %v)", c.Entity, c.Attribute, c.Before, c.After)
+	}
+	return fmt.Sprintf("%v: %s", c.Entity, c.Kind)
+}
+
+//DiffSnapshots reports the changes of the organization
+//between t1 and t2, as seen by comparing the snapshots
+//taken at them, see DiffOrgSnapshots
+func (o *Organization) DiffSnapshots(t1 time.Time, t2 time.Time, assignments *AssignmentCollection) []OrgChange {
+	return DiffOrgSnapshots(o.OrgSnapshot(t1, assignments), o.OrgSnapshot(t2, assignments))
+}
+
+//DiffOrgSnapshots reports the units, positions and
+//assignments present in only one of the snapshots, followed
+//by the attribute changes of the organization and of the
+//units, positions, assignments and people present in both.
+//Attributes are compared at the pit of each snapshot, so
+//only TemporalAttributeBearers report attribute changes.
+//Changes are ordered by kind and then by the order the
+//units are walked in
+func DiffOrgSnapshots(before *OrgSnapshot, after *OrgSnapshot) []OrgChange {
+
+	left, right := collectSnapshot(before), collectSnapshot(after)
+
+	var changes []OrgChange
+	presence := func(l []TimeTrackedEntity, r []TimeTrackedEntity, added ChangeKind, removed ChangeKind) {
+		changes = appendMissing(changes, r, l, added)
+		changes = appendMissing(changes, l, r, removed)
+	}
+	presence(left.units, right.units, UnitCreated, UnitClosed)
+	presence(left.positions, right.positions, PositionAdded, PositionRemoved)
+	presence(left.assignments, right.assignments, AssignmentStarted, AssignmentEnded)
+
+	shared := []TimeTrackedEntity{before.Organization}
+	for _, group := range [][2][]TimeTrackedEntity{
+		{left.units, right.units},
+		{left.positions, right.positions},
+		{left.assignments, right.assignments},
+		{left.people, right.people},
+	} {
+		shared = append(shared, common(group[0], group[1])...)
+	}
+	for _, e := range shared {
+		changes = append(changes, attributeChanges(e, before.At, after.At)...)
+	}
+
+	return changes
+}
+
+//snapshotContents lists the entities of a snapshot
+//in the order its units are walked
+type snapshotContents struct {
+	units, positions, assignments, people []TimeTrackedEntity
+}
+
+//collectSnapshot lists the entities of s
+func collectSnapshot(s *OrgSnapshot) snapshotContents {
+
+	var contents snapshotContents
+	seen := make(map[*Person]bool)
+	s.Walk(func(u *SnapshotUnit) bool {
+		contents.units = append(contents.units, u.Unit)
+		for _, p := range u.positions {
+			contents.positions = append(contents.positions, p.Position)
+			if p.Assignment != nil {
+				contents.assignments = append(contents.assignments, p.Assignment)
+			}
+			if p.Holder != nil && !seen[p.Holder] {
+				seen[p.Holder] = true
+				contents.people = append(contents.people, p.Holder)
+			}
+		}
+		return true
+	})
+
+	return contents
+}
+
+//appendMissing appends a change of the given kind
+//for every entity of from that is not in other
+func appendMissing(changes []OrgChange, from []TimeTrackedEntity, other []TimeTrackedEntity, kind ChangeKind) []OrgChange {
+
+	present := make(map[TimeTrackedEntity]bool, len(other))
+	for _, e := range other {
+		present[e] = true
+	}
+	for _, e := range from {
+		if !present[e] {
+			changes = append(changes, OrgChange{Kind: kind, Entity: e})
+		}
+	}
+
+	return changes
+}
+
+//common returns the entities of a that are also in b
+func common(a []TimeTrackedEntity, b []TimeTrackedEntity) []TimeTrackedEntity {
+
+	present := make(map[TimeTrackedEntity]bool, len(b))
+	for _, e := range b {
+		present[e] = true
+	}
+
+	var shared []TimeTrackedEntity
+	for _, e := range a {
+		if present[e] {
+			shared = append(shared, e)
+		}
+	}
+
+	return shared
+}
+
+//attributeChanges compares the attributes e held at t1
+//and t2, if it is a TemporalAttributeBearer
+func attributeChanges(e TimeTrackedEntity, t1 time.Time, t2 time.Time) []OrgChange {
+
+	bearer, ok := e.(TemporalAttributeBearer)
+	if !ok {
+		return nil
+	}
+
+	names := bearer.GetAttributeNamesAt(t1)
+	for _, name := range bearer.GetAttributeNamesAt(t2) {
+		if _, err := bearer.GetAttributeAt(name, t1); err != nil {
+			names = append(names, name)
+		}
+	}
+
+	var changes []OrgChange
+	for _, name := range names {
+		// a missing value is reported as nil
+		previous, _ := bearer.GetAttributeAt(name, t1)
+		current, _ := bearer.GetAttributeAt(name, t2)
+		if !reflect.DeepEqual(previous, current) {
+			changes = append(changes, OrgChange{Kind: AttributeChanged, Entity: e, Attribute: name, Before: previous, After: current})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	org, _ := NewOrganization("acme", "Acme", day(1), NilTime())
+	sales, _ := NewOrgUnit(org, nil, "sales", "Sales", day(1), NilTime())
+	emea, _ := NewOrgUnit(org, sales, "emea", "EMEA", day(1), day(15))
+	apac, _ := NewOrgUnit(org, sales, "apac", "APAC", day(10), NilTime())
+	head, _ := NewPosition(sales, "head", "Head of Sales", day(1), NilTime())
+	lead, _ := NewPosition(apac, "lead", "APAC Lead", day(10), NilTime())
+	ann, _ := NewPerson("ann", "Ann", day(1), NilTime())
+	bob, _ := NewPerson("bob", "Bob", day(1), NilTime())
+
+	var assignments AssignmentCollection
+	annHead, _ := NewAssignment(ann, head, day(1), day(12))
+	bobHead, _ := NewAssignment(bob, head, day(12), NilTime())
+	annLead, _ := NewAssignment(ann, lead, day(12), NilTime())
+	for _, a := range []*Assignment{annHead, bobHead, annLead} {
+		assignments.AddAssignment(a)
+	}
+
+	sales.SetAttributeAt("costCenter", "CC-1", day(1))
+	sales.SetAttributeAt("costCenter", "CC-2", day(11))
+	ann.SetAttributeAt("band", "B1", day(1))
+	ann.SetAttributeAt("band", "B2", day(14))
+	apac.SetAttributeAt("region", "asia", day(10))
+
+	changes := org.DiffSnapshots(day(5), day(20), &assignments)
+	expected := []OrgChange{
+		{Kind: UnitCreated, Entity: apac},
+		{Kind: UnitClosed, Entity: emea},
+		{Kind: PositionAdded, Entity: lead},
+		{Kind: AssignmentStarted, Entity: bobHead},
+		{Kind: AssignmentStarted, Entity: annLead},
+		{Kind: AssignmentEnded, Entity: annHead},
+		{Kind: AttributeChanged, Entity: sales, Attribute: "costCenter", Before: "CC-1", After: "CC-2"},
+		{Kind: AttributeChanged, Entity: ann, Attribute: "band", Before: "B1", After: "B2"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], changes[i])
+		}
+	}
+
+	changes = org.DiffSnapshots(day(13), day(20), &assignments)
+	if len(changes) != 2 || changes[0].Kind != UnitClosed ||
+		changes[1] != (OrgChange{Kind: AttributeChanged, Entity: ann, Attribute: "band", Before: "B1", After: "B2"}) {
+		t.Errorf("unexpected changes %v", changes)
+	}
+
+	if changes := org.DiffSnapshots(day(20), day(20), &assignments); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
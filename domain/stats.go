@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+)
+
+//TreeStats holds introspection data about the interval
+//tree behind a TimeTrackedEntityCollection
+type TreeStats struct {
+	// number of entities in the collection
+	Nodes int
+	// number of levels of the tree
+	Height int
+	// the height of a perfectly balanced
+	// tree holding the same number of nodes
+	OptimalHeight int
+	// height of the left subtree of the root
+	// minus the height of the right one
+	BalanceFactor int
+	// number of queries run since the tree was
+	// last rebuilt, see Between
+	Queries int
+	// average number of nodes those queries
+	// visited, 0 if none was run
+	AvgQueryVisits float64
+}
+
+//Imbalance returns the ratio of the actual height to the
+//optimal height of the tree. A perfectly balanced tree has
+//an imbalance of 1, an empty tree has an imbalance of 0
+func (s TreeStats) Imbalance() float64 {
+	if s.OptimalHeight == 0 {
+		return 0
+	}
+	return float64(s.Height) / float64(s.OptimalHeight)
+}
+
+//Stats returns statistics about the shape of the
+//underlying interval tree and the cost of the queries
+//run on it. It walks the whole tree to measure its
+//height, so it runs in O(n)
+func (ts *TimeTrackedEntityCollection[T]) Stats() TreeStats {
+
+	var leftHeight, rightHeight int
+	if ts.root != nil {
+		leftHeight = nodeHeight(ts.root.left)
+		rightHeight = nodeHeight(ts.root.right)
+	}
+	stats := TreeStats{
+		Nodes:         ts.noOfNodes,
+		Height:        nodeHeight(ts.root),
+		OptimalHeight: optimalHeight(ts.noOfNodes),
+		BalanceFactor: leftHeight - rightHeight,
+		Queries:       int(atomic.LoadInt64(&ts.queries)),
+	}
+	if stats.Queries > 0 {
+		stats.AvgQueryVisits = float64(atomic.LoadInt64(&ts.visits)) / float64(stats.Queries)
+	}
+
+	return stats
+}
+
+//SetRebuildThreshold makes the collection rebuild itself
+//after an insertion, whenever the imbalance of the tree
+//exceeds threshold. Values should be greater than 1, as
+//anything lower would rebuild the tree on every insertion.
+//A threshold of 0 (the default) disables automatic rebuilds
+func (ts *TimeTrackedEntityCollection[T]) SetRebuildThreshold(threshold float64) {
+	ts.rebuildThreshold = threshold
+}
+
+//Rebuild reconstructs the tree so that it is optimally
+//balanced. It runs in O(n) and is meant to be called
+//during maintenance windows, after many insertions in
+//start order have skewed the tree
+func (ts *TimeTrackedEntityCollection[T]) Rebuild() {
+	// cannot fail without a cancellable context
+	_ = ts.RebuildContext(context.Background(), nil)
+}
+
+//needsRebuild checks if the imbalance of the tree exceeds
+//the configured rebuild threshold. It runs in constant time,
+//judging by the maintained height rather than walking the
+//tree like Stats. After removals that height is an upper
+//bound, so a rebuild may come early, and resets it
+func (ts *TimeTrackedEntityCollection[T]) needsRebuild() bool {
+
+	if ts.rebuildThreshold <= 0 {
+		return false
+	}
+	stats := TreeStats{Height: ts.height, OptimalHeight: optimalHeight(ts.noOfNodes)}
+
+	return stats.Imbalance() > ts.rebuildThreshold
+}
+
+//buildBalanced links the already sorted nodes into a
+//balanced tree, recomputing the max of every node, and
+//returns its root
+func buildBalanced[T TimeTrackedEntity](nodes []*intervalNode[T]) *intervalNode[T] {
+
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	middle := len(nodes) / 2
+	n := nodes[middle]
+	n.left = buildBalanced(nodes[:middle])
+	n.right = buildBalanced(nodes[middle+1:])
+	n.updateMax()
+
+	return n
+}
+
+//nodeHeight returns the number of levels below and including n
+func nodeHeight[T TimeTrackedEntity](n *intervalNode[T]) int {
+
+	if n == nil {
+		return 0
+	}
+
+	left, right := nodeHeight(n.left), nodeHeight(n.right)
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+//optimalHeight returns the height of a perfectly
+//balanced binary tree holding n nodes
+func optimalHeight(n int) int {
+	return int(math.Ceil(math.Log2(float64(n + 1))))
+}
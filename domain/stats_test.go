@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+// addSkewed adds n consecutive days, in start order, which
+// degenerates the tree to a list
+func addSkewed(collection *EntityCollection, n int) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		collection.AddEntity(createMockTTEntity(
+			start.AddDate(0, 0, i),
+			start.AddDate(0, 0, i+1)))
+	}
+}
+
+func TestStats(t *testing.T) {
+
+	collection := EntityCollection{}
+	if stats := collection.Stats(); stats.Nodes != 0 || stats.Height != 0 || stats.Imbalance() != 0 {
+		t.Errorf("unexpected stats for an empty collection %+v", stats)
+	}
+
+	addSkewed(&collection, 7)
+
+	stats := collection.Stats()
+	if stats.Nodes != 7 || stats.Height != 7 || stats.OptimalHeight != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.BalanceFactor != -6 {
+		t.Errorf("expected balance factor -6, got %d", stats.BalanceFactor)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+
+	collection := EntityCollection{}
+	addSkewed(&collection, 15)
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		NilTime()))
+
+	collection.Rebuild()
+
+	stats := collection.Stats()
+	if stats.Height != stats.OptimalHeight || stats.Height != nodeHeight(collection.root) {
+		t.Errorf("expected an optimally balanced tree, got %+v", stats)
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated after rebuild: %v", err)
+	}
+	if len(collection.Entities()) != 16 {
+		t.Errorf("expected 16 entities, got %d", len(collection.Entities()))
+	}
+}
+
+func TestAutomaticRebuild(t *testing.T) {
+
+	collection := EntityCollection{}
+	collection.SetRebuildThreshold(2)
+	addSkewed(&collection, 100)
+
+	stats := collection.Stats()
+	if stats.Imbalance() > 2 {
+		t.Errorf("expected imbalance below the threshold, got %+v", stats)
+	}
+	if collection.height != stats.Height {
+		t.Errorf("maintained height %d differs from the actual %d", collection.height, stats.Height)
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+
+	// after removals the maintained height is an upper
+	// bound, reset once the collection is emptied
+	for _, e := range collection.Entities()[:50] {
+		collection.RemoveEntity(e)
+	}
+	if collection.height < nodeHeight(collection.root) {
+		t.Errorf("maintained height %d below the actual %d", collection.height, nodeHeight(collection.root))
+	}
+	for _, e := range collection.Entities() {
+		collection.RemoveEntity(e)
+	}
+	if collection.height != 0 {
+		t.Errorf("expected the height of an empty collection to be 0, got %d", collection.height)
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+
+	collection := EntityCollection{}
+	addSkewed(&collection, 15)
+	last := time.Date(2020, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	if stats := collection.Stats(); stats.Queries != 0 || stats.AvgQueryVisits != 0 {
+		t.Errorf("expected no queries yet, got %+v", stats)
+	}
+
+	// on a list the last entity is found by visiting every node
+	collection.FindExistentAt(last)
+	collection.FindIntersecting(last, NilTime())
+	if stats := collection.Stats(); stats.Queries != 2 || stats.AvgQueryVisits != 15 {
+		t.Errorf("expected every node visited, got %+v", stats)
+	}
+
+	collection.Rebuild()
+	if stats := collection.Stats(); stats.Queries != 0 {
+		t.Errorf("expected the rebuild to reset the query stats, got %+v", stats)
+	}
+	if found := collection.FindExistentAt(last); len(found) != 1 {
+		t.Fatalf("unexpected entities %v", found)
+	}
+	if stats := collection.Stats(); stats.Queries != 1 || stats.AvgQueryVisits > float64(2*stats.Height) {
+		t.Errorf("expected a path of the balanced tree and its pruned siblings visited, got %+v", stats)
+	}
+}
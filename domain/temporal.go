@@ -0,0 +1,381 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/NTsiridis/orgopus/clock"
+)
+
+//ErrHistoryOverlap is returned when restoring a history
+//where two values of the same attribute overlap
+var ErrHistoryOverlap = errors.New("attribute values overlap")
+
+//TemporalAttributeBearer is an AttributeBearer that keeps the
+//history of its attributes, so their value at any pit can be
+//looked up. The methods of AttributeBearer act on the values
+//existent at the time they are called
+type TemporalAttributeBearer interface {
+	AttributeBearer
+
+	//SetAttributeAt sets the value of the attribute from the
+	//given pit onwards, ending the value it had until then,
+	//which is returned
+	SetAttributeAt(attrName string, value interface{}, from time.Time) (interface{}, error)
+
+	//GetAttributeAt returns the value the attribute had at pit
+	GetAttributeAt(attrName string, pit time.Time) (interface{}, error)
+
+	//GetAttributeNamesAt returns the names of the
+	//attributes that held a value at pit
+	GetAttributeNamesAt(pit time.Time) []string
+
+	//AttributeHistory returns every value the attribute
+	//has had, ordered by the pit it was set
+	AttributeHistory(attrName string) []*AttributeValue
+
+	//AttributeHistoryNames returns the names of the
+	//attributes that held a value at any pit
+	AttributeHistoryNames() []string
+}
+
+//AttributeValue is a value an attribute held during
+//an interval. It is a TimeTrackedEntity, so histories
+//are kept in a TimeTrackedEntityCollection
+type AttributeValue struct {
+	Name  string
+	Value interface{}
+	// the pit the value was set
+	From time.Time
+	// the pit the value was replaced or removed,
+	// zero while it is the current value
+	Until time.Time
+}
+
+//IsExistentAt checks if the value was held at pit
+func (v *AttributeValue) IsExistentAt(pit time.Time) bool {
+	return !v.From.After(pit) && (v.Until.IsZero() || v.Until.After(pit))
+}
+
+//ExistentFrom returns the pit the value was set
+func (v *AttributeValue) ExistentFrom() time.Time {
+	return v.From
+}
+
+//ValidUntil returns the pit the value was replaced,
+//or the zero time if it is still held
+func (v *AttributeValue) ValidUntil() time.Time {
+	return v.Until
+}
+
+//ActiveDuration returns how long the value was held,
+//up to now if it is still held
+func (v *AttributeValue) ActiveDuration() time.Duration {
+
+	until := v.Until
+	if until.IsZero() {
+		until = clock.Now()
+	}
+
+	return until.Sub(v.From)
+}
+
+//End replaces the value at the given pit
+func (v *AttributeValue) End(at time.Time) error {
+	v.Until = at
+	return nil
+}
+
+//String implementation of an attribute value
+func (v *AttributeValue) String() string {
+	return fmt.Sprintf("%s=%v [%s, %s)", v.Name, v.Value, canonicalTime(v.From), canonicalTime(v.Until))
+}
+
+//BaseTemporalAttributeBearer is an implementation of
+//TemporalAttributeBearer keeping the history of every
+//attribute in an interval tree. The methods of
+//AttributeBearer act on the pit told by its clock, see
+//SetClock. The zero value holds no attributes and is
+//ready to use
+type BaseTemporalAttributeBearer struct {
+	history map[string]*TimeTrackedEntityCollection[*AttributeValue]
+	clock   clock.Clock
+}
+
+//SetClock sets the clock telling the pit the methods of
+//AttributeBearer act on, so simulated time can drive the
+//history. A nil clock stands for the package level
+//clock.Default(), which is also used until SetClock is called
+func (b *BaseTemporalAttributeBearer) SetClock(c clock.Clock) {
+	b.clock = c
+}
+
+//currentTime returns the time of the clock of b
+func (b *BaseTemporalAttributeBearer) currentTime() time.Time {
+
+	if b.clock == nil {
+		return clock.Now()
+	}
+
+	return b.clock.Now()
+}
+
+//GetAttributeNames returns the names of the attributes
+//that hold a value now, sorted alphabetically
+func (b *BaseTemporalAttributeBearer) GetAttributeNames() []string {
+	return b.GetAttributeNamesAt(b.currentTime())
+}
+
+//GetAttributeNamesAt returns the names of the attributes
+//that held a value at pit, sorted alphabetically
+func (b *BaseTemporalAttributeBearer) GetAttributeNamesAt(pit time.Time) []string {
+
+	names := make([]string, 0, len(b.history))
+	for name := range b.history {
+		if _, err := b.GetAttributeAt(name, pit); err == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//AttributeHistoryNames returns the names of the attributes
+//that held a value at any pit, sorted alphabetically
+func (b *BaseTemporalAttributeBearer) AttributeHistoryNames() []string {
+
+	names := make([]string, 0, len(b.history))
+	for name, history := range b.history {
+		if history.noOfNodes > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//HasAttribute checks if the attribute holds a value now
+func (b *BaseTemporalAttributeBearer) HasAttribute(attrName string) bool {
+	_, err := b.GetAttributeAt(attrName, b.currentTime())
+	return err == nil
+}
+
+//GetAttribute returns the value the attribute holds now
+func (b *BaseTemporalAttributeBearer) GetAttribute(attrName string) (interface{}, error) {
+	return b.GetAttributeAt(attrName, b.currentTime())
+}
+
+//SetAttribute sets the value of the attribute from now on
+//and returns the previous one. A value set at the same pit
+//is replaced, and values set with SetAttributeAt from a
+//later pit are discarded, since they would otherwise
+//replace the new value
+func (b *BaseTemporalAttributeBearer) SetAttribute(attrName string, value interface{}) interface{} {
+
+	now := b.currentTime()
+
+	var replaced *AttributeValue
+	if history := b.history[attrName]; history != nil {
+		for _, v := range history.FindIntersecting(now, NilTime()) {
+			if v.From.Before(now) {
+				continue
+			}
+			history.RemoveEntity(v)
+			if v.From.Equal(now) {
+				replaced = v
+			}
+		}
+	}
+
+	// cannot fail, every value left starts before now
+	previous, _ := b.SetAttributeAt(attrName, value, now)
+	if replaced != nil {
+		previous = replaced.Value
+	}
+
+	return previous
+}
+
+//SetAttributeAt sets the value of the attribute from the
+//given pit onwards and returns the value it replaces, if
+//any. Values can only be appended to the history, so from
+//must be after the pit the latest value was set, otherwise
+//ErrEndBeforeStart is returned
+func (b *BaseTemporalAttributeBearer) SetAttributeAt(attrName string, value interface{}, from time.Time) (interface{}, error) {
+
+	if b.history == nil {
+		b.history = make(map[string]*TimeTrackedEntityCollection[*AttributeValue])
+	}
+	history := b.history[attrName]
+	if history == nil {
+		history = &TimeTrackedEntityCollection[*AttributeValue]{}
+		b.history[attrName] = history
+	}
+
+	var previous interface{}
+	if latest := b.latest(attrName); latest != nil {
+		if !from.After(latest.From) {
+			return nil, ErrEndBeforeStart
+		}
+		if latest.Until.IsZero() || latest.Until.After(from) {
+			previous = latest.Value
+			if latest.Until.IsZero() {
+				if err := history.EndEntity(latest, from); err != nil {
+					return nil, err
+				}
+			} else {
+				// the value was removed later than
+				// from, the new one takes over earlier
+				history.RemoveEntity(latest)
+				latest.Until = from
+				history.AddEntity(latest)
+			}
+		}
+	}
+
+	history.AddEntity(&AttributeValue{Name: attrName, Value: value, From: from})
+
+	return previous, nil
+}
+
+//RemoveAttributeAt ends the value of the attribute at the
+//given pit, after which the attribute holds no value until
+//it is set again. ErrAttributeNotFound is returned if the
+//attribute holds no open ended value
+func (b *BaseTemporalAttributeBearer) RemoveAttributeAt(attrName string, at time.Time) error {
+
+	latest := b.latest(attrName)
+	if latest == nil || !latest.Until.IsZero() {
+		return fmt.Errorf("%s: %w", attrName, ErrAttributeNotFound)
+	}
+
+	return b.history[attrName].EndEntity(latest, at)
+}
+
+//GetAttributeAt returns the value the attribute had at pit,
+//or ErrAttributeNotFound if it held no value then
+func (b *BaseTemporalAttributeBearer) GetAttributeAt(attrName string, pit time.Time) (interface{}, error) {
+
+	if history := b.history[attrName]; history != nil {
+		if found := history.FindExistentAt(pit); len(found) > 0 {
+			return found[0].Value, nil
+		}
+	}
+
+	return nil, ErrAttributeNotFound
+}
+
+//AttributeHistory returns every value the attribute has
+//had, ordered by the pit it was set. The values returned
+//must not be modified
+func (b *BaseTemporalAttributeBearer) AttributeHistory(attrName string) []*AttributeValue {
+
+	if history := b.history[attrName]; history != nil {
+		return history.Entities()
+	}
+
+	return nil
+}
+
+//RestoreHistory replaces the history of every attribute
+//with values, as returned by HistoryOf, so codecs can rebuild
+//a bearer exactly. Every value must start and end no earlier
+//than it starts, otherwise ErrEndBeforeStart is returned, and
+//values of the same attribute must not overlap, otherwise
+//ErrHistoryOverlap is returned. On error b is left as it was
+func (b *BaseTemporalAttributeBearer) RestoreHistory(values []AttributeValue) error {
+
+	byName := make(map[string][]*AttributeValue)
+	for i := range values {
+		v := values[i]
+		if v.From.IsZero() {
+			return fmt.Errorf("%s: %w", v.Name, ErrMissingStart)
+		}
+		if !v.Until.IsZero() && !v.Until.After(v.From) {
+			return fmt.Errorf("%s: %w", v.Name, ErrEndBeforeStart)
+		}
+		byName[v.Name] = append(byName[v.Name], &v)
+	}
+
+	history := make(map[string]*TimeTrackedEntityCollection[*AttributeValue], len(byName))
+	for name, attrValues := range byName {
+		sort.Slice(attrValues, func(i, j int) bool {
+			return attrValues[i].From.Before(attrValues[j].From)
+		})
+		for i := 1; i < len(attrValues); i++ {
+			previous := attrValues[i-1]
+			if previous.Until.IsZero() || previous.Until.After(attrValues[i].From) {
+				return fmt.Errorf("%s: %w", name, ErrHistoryOverlap)
+			}
+		}
+		history[name] = NewCollectionFromEntities(attrValues)
+	}
+	b.history = history
+
+	return nil
+}
+
+//latest returns the value of the attribute that was
+//set last, or nil if it was never set
+func (b *BaseTemporalAttributeBearer) latest(attrName string) *AttributeValue {
+
+	values := b.AttributeHistory(attrName)
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values[len(values)-1]
+}
+
+//AttributeAt returns the value the attribute of b had at pit.
+//Only TemporalAttributeBearers keep a history, so for other
+//bearers the current value is returned
+func AttributeAt(b AttributeBearer, attrName string, pit time.Time) (interface{}, error) {
+
+	if temporal, ok := b.(TemporalAttributeBearer); ok {
+		return temporal.GetAttributeAt(attrName, pit)
+	}
+
+	return b.GetAttribute(attrName)
+}
+
+//AttributesAt returns the attributes e held at pit if it is
+//an AttributeBearer, or nil otherwise, see AttributeAt
+func AttributesAt(e TimeTrackedEntity, pit time.Time) map[string]interface{} {
+
+	temporal, ok := e.(TemporalAttributeBearer)
+	if !ok {
+		return attributesOf(e)
+	}
+
+	attributes := make(map[string]interface{})
+	for _, name := range temporal.GetAttributeNamesAt(pit) {
+		if value, err := temporal.GetAttributeAt(name, pit); err == nil {
+			attributes[name] = value
+		}
+	}
+
+	return attributes
+}
+
+//historiesOf returns the history of every attribute of b,
+//keyed by name, with the pits in UTC so histories can be
+//compared deeply
+func historiesOf(b TemporalAttributeBearer) map[string]interface{} {
+
+	histories := make(map[string]interface{})
+	for _, name := range b.AttributeHistoryNames() {
+		values := b.AttributeHistory(name)
+		history := make([]*AttributeValue, len(values))
+		for i, v := range values {
+			history[i] = &AttributeValue{Name: v.Name, Value: v.Value, From: v.From.UTC(), Until: v.Until.UTC()}
+		}
+		histories[name] = history
+	}
+
+	return histories
+}
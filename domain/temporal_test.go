@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/clock"
+)
+
+func TestTemporalAttributes(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	b := &BaseTemporalAttributeBearer{}
+	var _ TemporalAttributeBearer = b
+
+	if previous, err := b.SetAttributeAt("band", "B1", day(1)); err != nil || previous != nil {
+		t.Errorf("unexpected result %v, %v", previous, err)
+	}
+	if previous, err := b.SetAttributeAt("band", "B2", day(10)); err != nil || previous != "B1" {
+		t.Errorf("unexpected result %v, %v", previous, err)
+	}
+	if _, err := b.SetAttributeAt("band", "B0", day(5)); err != ErrEndBeforeStart {
+		t.Errorf("expected ErrEndBeforeStart for a backdated value, got %v", err)
+	}
+	if err := b.RemoveAttributeAt("band", day(20)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := b.RemoveAttributeAt("band", day(21)); !errors.Is(err, ErrAttributeNotFound) {
+		t.Errorf("expected ErrAttributeNotFound, got %v", err)
+	}
+
+	cases := []struct {
+		pit      time.Time
+		expected interface{}
+	}{
+		{day(1), "B1"},
+		{day(9), "B1"},
+		{day(10), "B2"},
+		{day(19), "B2"},
+	}
+	for _, c := range cases {
+		if value, err := b.GetAttributeAt("band", c.pit); err != nil || value != c.expected {
+			t.Errorf("at %v: expected %v, got %v, %v", c.pit, c.expected, value, err)
+		}
+	}
+	for _, pit := range []time.Time{day(0), day(20)} {
+		if _, err := b.GetAttributeAt("band", pit); err != ErrAttributeNotFound {
+			t.Errorf("at %v: expected ErrAttributeNotFound, got %v", pit, err)
+		}
+	}
+	if b.HasAttribute("band") || len(b.GetAttributeNames()) != 0 {
+		t.Error("expected the removed attribute not to be held now")
+	}
+
+	history := b.AttributeHistory("band")
+	if len(history) != 2 || history[0].Value != "B1" || !history[0].Until.Equal(day(10)) ||
+		history[1].Value != "B2" || !history[1].Until.Equal(day(20)) {
+		t.Errorf("unexpected history %v", history)
+	}
+}
+
+func TestTemporalSetAttribute(t *testing.T) {
+
+	b := &BaseTemporalAttributeBearer{}
+	b.SetAttributeAt("location", "Athens", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b.SetAttributeAt("location", "Berlin", time.Now().AddDate(1, 0, 0))
+
+	if previous := b.SetAttribute("location", "Paris"); previous != "Athens" {
+		t.Errorf("expected the previous value Athens, got %v", previous)
+	}
+	if value, err := b.GetAttribute("location"); err != nil || value != "Paris" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	if history := b.AttributeHistory("location"); len(history) != 2 {
+		t.Errorf("expected the future value to be discarded, got %v", history)
+	}
+	if names := b.GetAttributeNames(); len(names) != 1 || names[0] != "location" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestTemporalSetAttributeClock(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	simulated := clock.NewSimulated(start)
+	b := &BaseTemporalAttributeBearer{}
+	b.SetClock(simulated)
+
+	b.SetAttribute("band", "B1")
+	if previous := b.SetAttribute("band", "B2"); previous != "B1" {
+		t.Errorf("expected the previous value B1, got %v", previous)
+	}
+	if history := b.AttributeHistory("band"); len(history) != 1 || history[0].Value != "B2" || !history[0].From.Equal(start) {
+		t.Errorf("expected the value set at the same pit to be replaced, got %v", history)
+	}
+
+	simulated.Advance(24 * time.Hour)
+	b.SetAttribute("band", "B3")
+	if value, err := b.GetAttributeAt("band", start); err != nil || value != "B2" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	if value, err := b.GetAttribute("band"); err != nil || value != "B3" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	if names := b.AttributeHistoryNames(); len(names) != 1 || names[0] != "band" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestAttributesAtPit(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	ann, _ := NewPerson("ann", "Ann", day(1), NilTime())
+	ann.SetAttributeAt("site", "Athens", day(1))
+	ann.SetAttributeAt("site", "Patras", day(10))
+	collection := EntityCollection{}
+	collection.AddEntity(ann)
+
+	groups := collection.GroupBy([]string{"site"}, Count(), day(5))
+	if len(groups) != 1 || groups[0].Value != "Athens" {
+		t.Errorf("expected the value held at the pit, got %v", groups)
+	}
+
+	// a person that only ever was in Athens
+	bob, _ := NewPerson("ann", "Ann", day(1), NilTime())
+	bob.SetAttributeAt("site", "Athens", day(1))
+	other := EntityCollection{}
+	other.AddEntity(bob)
+
+	if collection.Fingerprint(day(5)) != other.Fingerprint(day(5)) {
+		t.Error("expected the fingerprint to see the value held at the pit")
+	}
+	if collection.Fingerprint(day(10)) == other.Fingerprint(day(10)) {
+		t.Error("expected the fingerprint to see the later value")
+	}
+	if attributes := AttributesAt(ann, day(5)); attributes["site"] != "Athens" {
+		t.Errorf("unexpected attributes %v", attributes)
+	}
+	if m := CompareEntities(ann, bob); len(m) != 1 || m[0].Field != "attribute site" {
+		t.Errorf("expected the histories to differ, got %v", m)
+	}
+}
+
+func TestAttributeValueString(t *testing.T) {
+
+	v := &AttributeValue{Name: "band", Value: "B1", From: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if s := Format(v); s != "band=B1 [2020-01-01T00:00:00Z, -)" {
+		t.Errorf("unexpected rendering %q", s)
+	}
+}
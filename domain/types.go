@@ -1,7 +1,11 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -9,12 +13,28 @@ import (
 
 // --------------------  Time tracking related types ------------------
 
+//ErrEntityNotFound is returned when an entity
+//is not part of the collection
+var ErrEntityNotFound = errors.New("entity not found in the collection")
+
+//ErrEntityNotEndable is returned when ending an
+//entity that doesn't implement EndableEntity
+var ErrEntityNotEndable = errors.New("entity cannot be ended")
+
+//ErrEntityAlreadyEnded is returned when ending an
+//entity that already has an ending
+var ErrEntityAlreadyEnded = errors.New("entity has already ended")
+
+//ErrEndBeforeStart is returned when ending an entity
+//at a pit that is not after its start
+var ErrEndBeforeStart = errors.New("entity cannot end before it starts")
+
 //TimeTrackedEntity is an interface that is obeyed
 //from all objects that have a time dimension, meaning
 //that come into existence in some specific point in time (pit)
 //and may stop being in a later pit. A TimeTrackedEntity
 //cannot be "stopped" in a pit and then later come again
-//"alive"
+// "alive"
 type TimeTrackedEntity interface {
 
 	//IsExistentAt returns true if the
@@ -42,6 +62,20 @@ type TimeTrackedEntity interface {
 	ActiveDuration() time.Duration
 }
 
+//EndableEntity is a TimeTrackedEntity that can be
+//terminated. Since the ending of an entity determines
+//its place in a TimeTrackedEntityCollection, entities
+//held by a collection should be ended through
+//EndEntity and not directly
+type EndableEntity interface {
+	TimeTrackedEntity
+
+	//End sets the pit this entity stops
+	//existing. It is only called on active
+	//entities with a pit after their start
+	End(at time.Time) error
+}
+
 //------------------------------------------------------------------
 
 //TimeTrackedEntityCollection is a structure used
@@ -49,18 +83,34 @@ type TimeTrackedEntity interface {
 //utility functions for searching and filtering
 //them.
 //It is based on an augmented interval tree
-type TimeTrackedEntityCollection struct {
-	root      *intervalNode
+type TimeTrackedEntityCollection[T TimeTrackedEntity] struct {
+	root      *intervalNode[T]
 	noOfNodes int
+	// number of levels of the tree, an upper
+	// bound after removals until the next rebuild
+	height int
+	// ratio of height to optimal height that
+	// triggers an automatic rebuild, 0 disables it
+	rebuildThreshold float64
+	// the queries run since the last rebuild and the
+	// nodes they visited, updated atomically as queries
+	// run concurrently under the read lock of
+	// ConcurrentTimeTrackedEntityCollection
+	queries, visits int64
 }
 
+//EntityCollection is the non generic form of the
+//collection, holding any TimeTrackedEntity. It is kept
+//for code written before the collection was generic
+type EntityCollection = TimeTrackedEntityCollection[TimeTrackedEntity]
+
 //String implementation traverse the collection and
 //return the result
-func (ts TimeTrackedEntityCollection) String() string {
+func (ts TimeTrackedEntityCollection[T]) String() string {
 
 	var str strings.Builder
 
-	ts.traverseNodes(ts.root, func(n *intervalNode, level int) {
+	ts.traverseNodes(ts.root, func(n *intervalNode[T], level int) {
 		str.WriteString("(" + strconv.Itoa(level) + ")" + n.String())
 	}, 0)
 
@@ -70,39 +120,196 @@ func (ts TimeTrackedEntityCollection) String() string {
 //AddEntity adds a new entity to the tracked
 //collections. It doesn't test if the entity
 //already exists in the collection
-func (ts *TimeTrackedEntityCollection) AddEntity(e TimeTrackedEntity) {
+func (ts *TimeTrackedEntityCollection[T]) AddEntity(e T) {
 
-	newNodeToInsert := &intervalNode{
+	newNodeToInsert := &intervalNode[T]{
 		entity: e,
 		max:    e.ValidUntil(),
 		left:   nil,
 		right:  nil,
 	}
 
-	ts.root = ts.insertNode(ts.root, newNodeToInsert)
+	ts.root = ts.insertNode(ts.root, newNodeToInsert, 1)
 	ts.noOfNodes++
+
+	if ts.needsRebuild() {
+		ts.Rebuild()
+	}
 }
 
-func (ts *TimeTrackedEntityCollection) intersectNode(tmp *intervalNode, searchFor TimeTrackedEntity, foundSoFar []TimeTrackedEntity) {
+//RemoveEntity removes e from the collection and returns
+//true, or returns false if e was not part of it. Entities
+//are matched with ==, or by deep equality for types that
+//are not comparable
+func (ts *TimeTrackedEntityCollection[T]) RemoveEntity(e T) bool {
+
+	var removed bool
+	ts.root, removed = ts.removeNode(ts.root, &intervalNode[T]{entity: e}, e)
+	if removed {
+		ts.noOfNodes--
+		if ts.root == nil {
+			ts.height = 0
+		}
+	}
 
-	if tmp == nil {
-		return
+	return removed
+}
+
+//EndEntity terminates the active entity e at the given pit
+//and moves its node to the place the new ending dictates, so
+//subsequent queries take the ending into account. e must be
+//part of the collection and implement EndableEntity
+func (ts *TimeTrackedEntityCollection[T]) EndEntity(e T, at time.Time) error {
+
+	endable, ok := TimeTrackedEntity(e).(EndableEntity)
+	if !ok {
+		return ErrEntityNotEndable
 	}
+	if !e.ValidUntil().IsZero() {
+		return ErrEntityAlreadyEnded
+	}
+	if !at.After(e.ExistentFrom()) {
+		return ErrEndBeforeStart
+	}
+
+	// the node is removed before the ending
+	// changes, while it can still be found
+	if !ts.RemoveEntity(e) {
+		return ErrEntityNotFound
+	}
+
+	err := endable.End(at)
+	ts.AddEntity(e)
+
+	return err
+}
+
+//Entities returns all the entities of the collection
+//ordered by their starting point
+func (ts *TimeTrackedEntityCollection[T]) Entities() []T {
 
-	if !searchFor.ValidUntil().IsZero() {
-		if !(compareEndTime(tmp.entity.ExistentFrom(), searchFor.ValidUntil()) < 0 ||
-			compareEndTime(tmp.entity.ValidUntil(), searchFor.ExistentFrom()) > 0) {
+	entities := make([]T, 0, ts.noOfNodes)
+	ts.traverseNodes(ts.root, func(n *intervalNode[T], level int) {
+		entities = append(entities, n.entity)
+	}, 0)
+
+	return entities
+}
 
+//WriteCanonical writes a deterministic rendering of the
+//collection to w, suitable for exports that are compared
+//or hashed. Every entity is written on its own line with its
+//interval in UTC (an open end is written as "-") followed by
+//its attributes sorted by name, if it is an AttributeBearer.
+//Lines are sorted so the output does not depend on the shape
+//of the tree
+func (ts *TimeTrackedEntityCollection[T]) WriteCanonical(w io.Writer) error {
+
+	for _, line := range canonicalLines(toEntities(ts.Entities()), attributesOf) {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
 		}
 	}
 
+	return nil
+}
+
+//toEntities converts a slice of a concrete
+//entity type to a slice of TimeTrackedEntity
+func toEntities[T TimeTrackedEntity](entities []T) []TimeTrackedEntity {
+
+	converted := make([]TimeTrackedEntity, len(entities))
+	for i, e := range entities {
+		converted[i] = e
+	}
+
+	return converted
+}
+
+//canonicalLines renders the entities in canonical form,
+//with the attributes returned by attributes, sorted so
+//their order doesn't matter
+func canonicalLines(entities []TimeTrackedEntity, attributes func(TimeTrackedEntity) map[string]interface{}) []string {
+
+	lines := make([]string, 0, len(entities))
+	for _, e := range entities {
+		lines = append(lines, canonicalLine(e, attributes(e)))
+	}
+	sort.Strings(lines)
+
+	return lines
+}
+
+//canonicalLine renders a single entity
+//along with the given attributes
+func canonicalLine(e TimeTrackedEntity, attributes map[string]interface{}) string {
+
+	var str strings.Builder
+
+	str.WriteString(canonicalTime(e.ExistentFrom()))
+	str.WriteString("\t")
+	str.WriteString(canonicalTime(e.ValidUntil()))
+	str.WriteString("\t")
+	str.WriteString(Format(e))
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		str.WriteString(fmt.Sprintf("\t%s=%v", name, attributes[name]))
+	}
+
+	return str.String()
+}
+
+//canonicalTime normalizes t to UTC so the same pit is
+//always rendered the same way
+func canonicalTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+//FindIntersecting returns the entities that exist at some
+//point of [from, to), ordered by their starting point. A zero
+//to means the search is open ended, so every entity that is
+//still existent at or after from is returned
+func (ts *TimeTrackedEntityCollection[T]) FindIntersecting(from time.Time, to time.Time) []T {
+
+	found := make([]T, 0)
+	for e := range ts.Between(from, to) {
+		found = append(found, e)
+	}
+
+	return found
+}
+
+//FindExistentAt returns the entities that are existent
+//at pit, ordered by their starting point
+func (ts *TimeTrackedEntityCollection[T]) FindExistentAt(pit time.Time) []T {
+
+	found := make([]T, 0)
+	for e := range ts.Between(pit, pit.Add(time.Nanosecond)) {
+		if e.IsExistentAt(pit) {
+			found = append(found, e)
+		}
+	}
+
+	return found
 }
 
 //InsertEntity adds an entity to the collections
-func (ts *TimeTrackedEntityCollection) insertNode(tmp *intervalNode, newNode *intervalNode) *intervalNode {
+//The level is the one tmp is at, starting from 1 for the root
+func (ts *TimeTrackedEntityCollection[T]) insertNode(tmp *intervalNode[T], newNode *intervalNode[T], level int) *intervalNode[T] {
 
 	// Check if we are in
 	if tmp == nil {
+		if level > ts.height {
+			ts.height = level
+		}
 		return newNode
 	}
 
@@ -115,29 +322,103 @@ func (ts *TimeTrackedEntityCollection) insertNode(tmp *intervalNode, newNode *in
 
 	//proceed with insertion
 	if tmp.compareTo(newNode) <= 0 {
-		if tmp.right == nil {
-			tmp.right = newNode
-		} else {
-			ts.insertNode(tmp.right, newNode)
-		}
+		tmp.right = ts.insertNode(tmp.right, newNode, level+1)
 	} else {
-		if tmp.left == nil {
-			tmp.left = newNode
-		} else {
-			ts.insertNode(tmp.left, newNode)
-		}
+		tmp.left = ts.insertNode(tmp.left, newNode, level+1)
 	}
 	return tmp
 }
 
-// visitorFunc is a function
-// that is used when visiting a node
-// of a TimeTrackedEntityCollection
-type visitorFunc func(n *intervalNode, level int)
+//removeNode removes the node holding e from the subtree of
+//tmp, recomputing the max of the nodes along the way, and
+//returns the new root of the subtree. key is a node holding
+//e, used to navigate the tree. As nodes with equal keys may
+//end up on both sides after a rebuild, both are searched
+func (ts *TimeTrackedEntityCollection[T]) removeNode(tmp *intervalNode[T], key *intervalNode[T], e T) (*intervalNode[T], bool) {
+
+	if tmp == nil {
+		return nil, false
+	}
+
+	removed := false
+	cmp := tmp.compareTo(key)
+
+	if cmp == 0 && sameEntity(tmp.entity, e) {
+		return unlinkNode(tmp), true
+	}
+	if cmp >= 0 {
+		tmp.left, removed = ts.removeNode(tmp.left, key, e)
+	}
+	if !removed && cmp <= 0 {
+		tmp.right, removed = ts.removeNode(tmp.right, key, e)
+	}
+
+	if removed {
+		tmp.updateMax()
+	}
+
+	return tmp, removed
+}
+
+//unlinkNode removes n from its subtree and returns
+//the node that takes its place
+func unlinkNode[T TimeTrackedEntity](n *intervalNode[T]) *intervalNode[T] {
+
+	if n.left == nil {
+		return n.right
+	}
+	if n.right == nil {
+		return n.left
+	}
+
+	// replace n with its in order successor
+	right, successor := removeMin(n.right)
+	successor.left = n.left
+	successor.right = right
+	successor.updateMax()
+
+	return successor
+}
+
+//removeMin detaches the leftmost node of the subtree of n
+//and returns the new root of the subtree along with it
+func removeMin[T TimeTrackedEntity](n *intervalNode[T]) (*intervalNode[T], *intervalNode[T]) {
+
+	if n.left == nil {
+		return n.right, n
+	}
+
+	var min *intervalNode[T]
+	n.left, min = removeMin(n.left)
+	n.updateMax()
+
+	return n, min
+}
+
+//sameEntity checks if a and b are the same entity, falling
+//back to deep equality for values that can't be compared,
+//including comparable types holding a slice or a map in an
+//interface field
+func sameEntity(a TimeTrackedEntity, b TimeTrackedEntity) bool {
+
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if reflect.ValueOf(a).Comparable() && reflect.ValueOf(b).Comparable() {
+		return a == b
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+//visitorFunc is a function
+//that is used when visiting a node
+//of a TimeTrackedEntityCollection
+type visitorFunc[T TimeTrackedEntity] func(n *intervalNode[T], level int)
 
 //traverseNodes , performa a pre order traversal and calls visitor
 //in every node visited
-func (ts *TimeTrackedEntityCollection) traverseNodes(n *intervalNode, visitor visitorFunc, currentLevel int) {
+func (ts *TimeTrackedEntityCollection[T]) traverseNodes(n *intervalNode[T], visitor visitorFunc[T], currentLevel int) {
 
 	if n == nil {
 		return
@@ -158,6 +439,72 @@ func (ts *TimeTrackedEntityCollection) traverseNodes(n *intervalNode, visitor vi
 
 }
 
+//CheckInvariants walks the whole tree and verifies that
+//it is still a valid augmented interval tree. That is, an
+//in order traversal yields the entities sorted by their
+//starting point, every node keeps the latest ending of its
+//subtree in max and the tree holds as many nodes as were
+//added. The first violation found is returned as an error
+func (ts *TimeTrackedEntityCollection[T]) CheckInvariants() error {
+
+	var previous *intervalNode[T]
+	var orderErr error
+	ts.traverseNodes(ts.root, func(n *intervalNode[T], level int) {
+		if orderErr == nil && previous != nil && previous.compareTo(n) > 0 {
+			orderErr = fmt.Errorf("node %v is placed after %v", n, previous)
+		}
+		previous = n
+	}, 0)
+	if orderErr != nil {
+		return orderErr
+	}
+
+	_, count, err := ts.checkNode(ts.root)
+	if err != nil {
+		return err
+	}
+
+	if count != ts.noOfNodes {
+		return fmt.Errorf("tree holds %d nodes but %d were added", count, ts.noOfNodes)
+	}
+
+	return nil
+}
+
+//checkNode verifies the max field of n and its subtree and
+//returns the latest ending found below n along with the
+//number of nodes visited
+func (ts *TimeTrackedEntityCollection[T]) checkNode(n *intervalNode[T]) (time.Time, int, error) {
+
+	if n == nil {
+		return NilTime(), 0, nil
+	}
+
+	leftMax, leftCount, err := ts.checkNode(n.left)
+	if err != nil {
+		return NilTime(), 0, err
+	}
+
+	rightMax, rightCount, err := ts.checkNode(n.right)
+	if err != nil {
+		return NilTime(), 0, err
+	}
+
+	max := n.entity.ValidUntil()
+	if n.left != nil && compareEndTime(leftMax, max) > 0 {
+		max = leftMax
+	}
+	if n.right != nil && compareEndTime(rightMax, max) > 0 {
+		max = rightMax
+	}
+
+	if compareEndTime(n.max, max) != 0 {
+		return NilTime(), 0, fmt.Errorf("node %v should have max %v", n, max)
+	}
+
+	return max, leftCount + rightCount + 1, nil
+}
+
 //-----------------------------------------------------------
 //                   Utility functions
 //-----------------------------------------------------------
@@ -169,11 +516,11 @@ func NilTime() time.Time {
 	return time.Time{}
 }
 
-// Compares two ending times , taking into account
-// the concept of "not ended yet".
-// Returns -1 if a ends before b
-// Returns 1 if a ends after b
-// Return 0 if a and b are equal (includes the case for Zero Time)
+//Compares two ending times , taking into account
+//the concept of "not ended yet".
+//Returns -1 if a ends before b
+//Returns 1 if a ends after b
+//Return 0 if a and b are equal (includes the case for Zero Time)
 func compareEndTime(a time.Time, b time.Time) int {
 
 	if a.IsZero() {
@@ -205,16 +552,16 @@ func compareEndTime(a time.Time, b time.Time) int {
 
 //intervalNode is a concrete augmented node
 //of the interval tree
-type intervalNode struct {
+type intervalNode[T TimeTrackedEntity] struct {
 	// the entity that is kept in the node
-	entity TimeTrackedEntity
+	entity T
 	// the maximum ending time of the
 	// tree below this node
 	max time.Time
 	// left subtree
-	left *intervalNode
+	left *intervalNode[T]
 	// right subtree
-	right *intervalNode
+	right *intervalNode[T]
 }
 
 //compareTo , compares a node with another. The comparison
@@ -222,7 +569,7 @@ type intervalNode struct {
 //entity and subsequently to the duration of the entity.
 //Returns -1 if n starts before the compare to node and 1 otherwise.
 //If they are equal it retuns 0
-func (n intervalNode) compareTo(anotherNode *intervalNode) int {
+func (n intervalNode[T]) compareTo(anotherNode *intervalNode[T]) int {
 
 	if n.entity.ExistentFrom().Before(anotherNode.entity.ExistentFrom()) {
 		return -1
@@ -233,9 +580,22 @@ func (n intervalNode) compareTo(anotherNode *intervalNode) int {
 	return 1
 }
 
+//updateMax recomputes the max of n from its
+//entity and the max of its children
+func (n *intervalNode[T]) updateMax() {
+
+	n.max = n.entity.ValidUntil()
+	if n.left != nil && compareEndTime(n.left.max, n.max) > 0 {
+		n.max = n.left.max
+	}
+	if n.right != nil && compareEndTime(n.right.max, n.max) > 0 {
+		n.max = n.right.max
+	}
+}
+
 //String implementation of a node
-func (n intervalNode) String() string {
-	return fmt.Sprintf("[E:%v M:%v]", n.entity, n.max)
+func (n intervalNode[T]) String() string {
+	return fmt.Sprintf("[E:%s M:%v]", Format(n.entity), n.max)
 }
 
 //------------------------------------------------
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,7 +82,7 @@ func createMockTTEntity(start time.Time, end time.Time) TimeTrackedEntity {
 
 func TestAddEntityToSlice(t *testing.T) {
 
-	collection := TimeTrackedEntityCollection{}
+	collection := EntityCollection{}
 
 	collection.AddEntity(createMockTTEntity(
 		time.Now(),
@@ -99,3 +100,335 @@ func TestAddEntityToSlice(t *testing.T) {
 	fmt.Printf("Collection:\n%v\n", collection)
 
 }
+
+func TestCheckInvariants(t *testing.T) {
+
+	collection := EntityCollection{}
+
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 1, 2, 15, 30, 10, 0, time.Local),
+		time.Date(2020, 1, 4, 15, 30, 10, 0, time.Local)))
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 1, 6, 15, 30, 10, 0, time.Local),
+		time.Date(2020, 1, 8, 15, 30, 10, 0, time.Local)))
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 1, 1, 15, 30, 10, 0, time.Local),
+		NilTime()))
+
+	if err := collection.CheckInvariants(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// corrupt the augmented value of the root
+	collection.root.max = time.Date(2020, 1, 4, 15, 30, 10, 0, time.Local)
+	if err := collection.CheckInvariants(); err == nil {
+		t.Error("expected an error for a wrong max value")
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+
+	collection := EntityCollection{}
+
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 1, 6, 15, 30, 10, 0, time.UTC),
+		time.Date(2020, 1, 8, 15, 30, 10, 0, time.UTC)))
+	collection.AddEntity(createMockTTEntity(
+		time.Date(2020, 1, 2, 17, 30, 10, 0, time.FixedZone("EET", 2*60*60)),
+		NilTime()))
+
+	var str strings.Builder
+	if err := collection.WriteCanonical(&str); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(str.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "2020-01-02T15:30:10Z\t-\t") {
+		t.Errorf("unexpected first line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "2020-01-06T15:30:10Z\t2020-01-08T15:30:10Z\t") {
+		t.Errorf("unexpected second line %q", lines[1])
+	}
+}
+
+// intersectionFixture returns a collection and the entities
+// it holds, some of them open ended
+func intersectionFixture() (*EntityCollection, []TimeTrackedEntity) {
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	entities := []TimeTrackedEntity{
+		createMockTTEntity(day(1), day(3)),
+		createMockTTEntity(day(2), NilTime()),
+		createMockTTEntity(day(4), day(6)),
+		createMockTTEntity(day(5), day(10)),
+		createMockTTEntity(day(8), NilTime()),
+		createMockTTEntity(day(12), day(13)),
+	}
+
+	collection := &EntityCollection{}
+	for _, e := range entities {
+		collection.AddEntity(e)
+	}
+
+	return collection, entities
+}
+
+func TestFindIntersecting(t *testing.T) {
+
+	collection, entities := intersectionFixture()
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		from, to time.Time
+		expected []TimeTrackedEntity
+	}{
+		// the end of an entity is exclusive
+		{day(3), day(4), []TimeTrackedEntity{entities[1]}},
+		{day(3), day(5), []TimeTrackedEntity{entities[1], entities[2]}},
+		{day(9), day(12), []TimeTrackedEntity{entities[1], entities[3], entities[4]}},
+		// open ended search
+		{day(11), NilTime(), []TimeTrackedEntity{entities[1], entities[4], entities[5]}},
+		{day(1), NilTime(), entities},
+		// empty search
+		{day(5), day(5), []TimeTrackedEntity{}},
+	}
+
+	for _, c := range cases {
+		found := collection.FindIntersecting(c.from, c.to)
+		if len(found) != len(c.expected) {
+			t.Errorf("[%v, %v): expected %v, got %v", c.from, c.to, c.expected, found)
+			continue
+		}
+		for i := range found {
+			if found[i] != c.expected[i] {
+				t.Errorf("[%v, %v): expected %v, got %v", c.from, c.to, c.expected, found)
+				break
+			}
+		}
+	}
+}
+
+func TestFindExistentAt(t *testing.T) {
+
+	collection, entities := intersectionFixture()
+
+	found := collection.FindExistentAt(time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC))
+	if len(found) != 2 || found[0] != entities[1] || found[1] != entities[3] {
+		t.Errorf("unexpected entities %v", found)
+	}
+
+	found = collection.FindExistentAt(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(found) != 2 || found[0] != entities[1] || found[1] != entities[4] {
+		t.Errorf("expected only the open ended entities, got %v", found)
+	}
+
+	found = collection.FindExistentAt(time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC))
+	if len(found) != 0 {
+		t.Errorf("expected no entities, got %v", found)
+	}
+}
+
+func TestFindIntersectingMatchesLinearScan(t *testing.T) {
+
+	collection := EntityCollection{}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 200; i++ {
+		entityStart := start.AddDate(0, 0, (i*37)%100)
+		end := NilTime()
+		if i%7 != 0 {
+			end = entityStart.AddDate(0, 0, i%13+i%5+1)
+		}
+		collection.AddEntity(createMockTTEntity(entityStart, end))
+	}
+
+	for d := 0; d < 120; d += 3 {
+		from := start.AddDate(0, 0, d)
+		to := from.AddDate(0, 0, 4)
+
+		expected := 0
+		for _, e := range collection.Entities() {
+			if ActiveDuring(e, NewPeriod(from, to)) {
+				expected++
+			}
+		}
+		if found := collection.FindIntersecting(from, to); len(found) != expected {
+			t.Errorf("[%v, %v): expected %d entities, got %d", from, to, expected, len(found))
+		}
+	}
+}
+
+type mockEndableEntity struct {
+	mockTTEntity
+}
+
+func (m *mockEndableEntity) End(at time.Time) error {
+	m.endAt = at
+	return nil
+}
+
+func TestRemoveEntity(t *testing.T) {
+
+	collection, entities := intersectionFixture()
+
+	for i, e := range []TimeTrackedEntity{entities[3], entities[0], entities[4]} {
+		if !collection.RemoveEntity(e) {
+			t.Fatalf("removal %d: expected the entity to be removed", i)
+		}
+		if err := collection.CheckInvariants(); err != nil {
+			t.Fatalf("removal %d: invariants violated: %v", i, err)
+		}
+	}
+
+	if collection.RemoveEntity(entities[3]) {
+		t.Error("expected a second removal to fail")
+	}
+	if collection.RemoveEntity(createMockTTEntity(entities[2].ExistentFrom(), entities[2].ValidUntil())) {
+		t.Error("expected an entity with the same interval not to be removed")
+	}
+
+	found := collection.FindIntersecting(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), NilTime())
+	if len(found) != 3 || found[0] != entities[1] || found[1] != entities[2] || found[2] != entities[5] {
+		t.Errorf("unexpected entities left %v", found)
+	}
+
+	// removing the open ended entity lowers the max of the root
+	collection.RemoveEntity(entities[1])
+	if collection.root.max != entities[5].ValidUntil() {
+		t.Errorf("expected max %v, got %v", entities[5].ValidUntil(), collection.root.max)
+	}
+}
+
+func TestRemoveEntityAfterRebuild(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	collection := EntityCollection{}
+	entities := make([]TimeTrackedEntity, 0)
+	for i := 0; i < 9; i++ {
+		// all entities share the same interval
+		e := createMockTTEntity(start, NilTime())
+		entities = append(entities, e)
+		collection.AddEntity(e)
+	}
+	collection.Rebuild()
+
+	for _, e := range entities {
+		if !collection.RemoveEntity(e) {
+			t.Fatalf("expected %v to be removed", e)
+		}
+		if err := collection.CheckInvariants(); err != nil {
+			t.Fatalf("invariants violated: %v", err)
+		}
+	}
+	if collection.root != nil {
+		t.Error("expected an empty tree")
+	}
+}
+
+// mockLabelledEntity is comparable, but compares with a
+// panic when its label holds a slice or a map
+type mockLabelledEntity struct {
+	*mockEndableEntity
+	label interface{}
+}
+
+func TestRemoveEntityHoldingUncomparable(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	collection := EntityCollection{}
+	a := mockLabelledEntity{&mockEndableEntity{createMockTTEntity(start, NilTime()).(mockTTEntity)}, []int{1}}
+	b := mockLabelledEntity{&mockEndableEntity{createMockTTEntity(start, NilTime()).(mockTTEntity)}, map[string]int{"b": 1}}
+	collection.AddEntity(a)
+	collection.AddEntity(b)
+
+	if err := collection.EndEntity(a, start.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("unexpected error ending %v: %v", a, err)
+	}
+	if !collection.RemoveEntity(b) {
+		t.Fatalf("expected %v to be removed", b)
+	}
+	if entities := collection.Entities(); len(entities) != 1 || entities[0].ValidUntil() != start.AddDate(0, 0, 1) {
+		t.Errorf("unexpected entities left %v", entities)
+	}
+}
+
+func TestEndEntity(t *testing.T) {
+
+	collection, _ := intersectionFixture()
+
+	e := &mockEndableEntity{createMockTTEntity(time.Date(2020, 1, 7, 0, 0, 0, 0, time.UTC), NilTime()).(mockTTEntity)}
+	collection.AddEntity(e)
+
+	if err := collection.EndEntity(e, time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)); err != ErrEndBeforeStart {
+		t.Errorf("expected ErrEndBeforeStart, got %v", err)
+	}
+
+	end := time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC)
+	if err := collection.EndEntity(e, end); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.ValidUntil() != end {
+		t.Errorf("expected the entity to end at %v, got %v", end, e.ValidUntil())
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+
+	for _, found := range collection.FindExistentAt(time.Date(2020, 1, 11, 0, 0, 0, 0, time.UTC)) {
+		if found == TimeTrackedEntity(e) {
+			t.Error("expected the ended entity not to be existent after its end")
+		}
+	}
+	if len(collection.FindIntersecting(end.Add(-time.Hour), end)) == 0 {
+		t.Error("expected the ended entity before its end")
+	}
+
+	if err := collection.EndEntity(e, end.AddDate(0, 0, 1)); err != ErrEntityAlreadyEnded {
+		t.Errorf("expected ErrEntityAlreadyEnded, got %v", err)
+	}
+	if err := collection.EndEntity(createMockTTEntity(end, NilTime()), end.AddDate(0, 0, 1)); err != ErrEntityNotEndable {
+		t.Errorf("expected ErrEntityNotEndable, got %v", err)
+	}
+	other := &mockEndableEntity{createMockTTEntity(end, NilTime()).(mockTTEntity)}
+	if err := collection.EndEntity(other, end.AddDate(0, 0, 1)); err != ErrEntityNotFound {
+		t.Errorf("expected ErrEntityNotFound, got %v", err)
+	}
+}
+
+func TestTypedCollection(t *testing.T) {
+
+	collection := TimeTrackedEntityCollection[mockTTEntity]{}
+
+	first := createMockTTEntity(
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)).(mockTTEntity)
+	second := createMockTTEntity(
+		time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		NilTime()).(mockTTEntity)
+	collection.AddEntity(second)
+	collection.AddEntity(first)
+
+	//the results are of the concrete type, so no
+	//assertion is needed to reach the id
+	found := collection.FindExistentAt(time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC))
+	if len(found) != 2 || found[0].id != first.id || found[1].id != second.id {
+		t.Errorf("unexpected entities %v", found)
+	}
+
+	if !collection.RemoveEntity(first) {
+		t.Fatalf("expected %v to be removed", first)
+	}
+	if entities := collection.Entities(); len(entities) != 1 || entities[0].id != second.id {
+		t.Errorf("expected only %v, got %v", second, entities)
+	}
+	if err := collection.CheckInvariants(); err != nil {
+		t.Errorf("invariants violated: %v", err)
+	}
+}
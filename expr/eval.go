@@ -0,0 +1,556 @@
+//Package expr is a small and safe expression language, in the
+//spirit of CEL, for computed attributes, validation rules and
+//filters of exports. Expressions have no side effects or loops,
+//their nesting is bounded and they can only read the variables
+//of the Env they are evaluated in, for example
+//
+//	attrs.grade >= 3 && attrs.site in ["Athens", "Patras"]
+//	active && from < date("2020-01-01")
+//
+//Numbers are float64, and numeric values found in the Env are
+//converted to float64 when read.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//Env holds the variables an expression can read
+type Env map[string]interface{}
+
+//Program is a compiled expression, safe
+//for concurrent evaluation
+type Program struct {
+	source string
+	root   node
+}
+
+//Compile parses the source of an expression
+func Compile(source string) (*Program, error) {
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+
+	return &Program{source: source, root: root}, nil
+}
+
+//String returns the source of the program
+func (p *Program) String() string {
+	return p.source
+}
+
+//Eval evaluates the program against env
+func (p *Program) Eval(env Env) (interface{}, error) {
+	return p.root.eval(env)
+}
+
+//EvalBool evaluates the program against env and
+//fails if the outcome is not a boolean
+func (p *Program) EvalBool(env Env) (bool, error) {
+
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q is not a condition, it yields %v", p.source, v)
+	}
+
+	return b, nil
+}
+
+//EntityEnv returns an Env describing e at pit:
+// "from" and "until" hold its interval (until is null if it
+//is open ended), "active" tells if it is existent at pit,
+// "pit" holds pit itself and "attrs" the attributes of e at
+//pit if it is an AttributeBearer, see domain.AttributesAt.
+//Further variables, like hierarchy
+//context, can be added to the returned Env
+func EntityEnv(e domain.TimeTrackedEntity, pit time.Time) Env {
+
+	var until interface{}
+	if !e.ValidUntil().IsZero() {
+		until = e.ValidUntil()
+	}
+
+	attrs := domain.AttributesAt(e, pit)
+	if attrs == nil {
+		attrs = make(map[string]interface{})
+	}
+
+	return Env{
+		"from":   e.ExistentFrom(),
+		"until":  until,
+		"active": e.IsExistentAt(pit),
+		"pit":    pit,
+		"attrs":  attrs,
+	}
+}
+
+// ---- syntax tree ----
+
+//node is a node of the syntax tree of an expression
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(env Env) (interface{}, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n variableNode) eval(env Env) (interface{}, error) {
+	value, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s", n.name)
+	}
+	return normalize(value), nil
+}
+
+type memberNode struct {
+	object node
+	name   string
+}
+
+//eval of a member yields null for missing fields and
+//for fields of null, so optional attributes can be
+//tested with "attrs.x == null"
+func (n memberNode) eval(env Env) (interface{}, error) {
+
+	object, err := n.object.eval(env)
+	if err != nil || object == nil {
+		return nil, err
+	}
+
+	m, ok := object.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot read field %s of %v", n.name, object)
+	}
+
+	return normalize(m[n.name]), nil
+}
+
+type indexNode struct {
+	object node
+	index  node
+}
+
+func (n indexNode) eval(env Env) (interface{}, error) {
+
+	object, err := n.object.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := index.(string); ok {
+		if m, ok := object.(map[string]interface{}); ok {
+			return normalize(m[key]), nil
+		}
+	}
+
+	i, ok := index.(float64)
+	v := reflect.ValueOf(object)
+	if !ok || object == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		return nil, fmt.Errorf("cannot index %v with %v", object, index)
+	}
+	if i != math.Trunc(i) || i < 0 || int(i) >= v.Len() {
+		return nil, fmt.Errorf("index %v out of range", index)
+	}
+
+	return normalize(v.Index(int(i)).Interface()), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n listNode) eval(env Env) (interface{}, error) {
+
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+
+	return list, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n unaryNode) eval(env Env) (interface{}, error) {
+
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "!" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %v", v)
+		}
+		return !b, nil
+	}
+
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %v", v)
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(env Env) (interface{}, error) {
+
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit the logical operators
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s expects conditions, got %v", n.op, left)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s expects conditions, got %v", n.op, right)
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "<", "<=", ">", ">=":
+		c, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+
+	if l, ok := left.(string); ok && n.op == "+" {
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %v and %v", n.op, left, right)
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return l / r, nil
+	}
+	if r == 0 {
+		return nil, errors.New("division by zero")
+	}
+	return math.Mod(l, r), nil
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n callNode) eval(env Env) (interface{}, error) {
+
+	f, ok := builtins[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", n.name)
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	v, err := f(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", n.name, err)
+	}
+
+	return v, nil
+}
+
+// ---- values ----
+
+//normalize converts the numeric kinds to float64
+//so expressions only deal with a single number type
+func normalize(value interface{}) interface{} {
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32:
+		return v.Float()
+	}
+
+	return value
+}
+
+//equal compares two values of an expression
+func equal(a interface{}, b interface{}) bool {
+
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Equal(tb)
+		}
+		return false
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+//compare orders two numbers, strings or times
+func compare(a interface{}, b interface{}) (int, error) {
+
+	switch l := a.(type) {
+	case float64:
+		if r, ok := b.(float64); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if r, ok := b.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case time.Time:
+		if r, ok := b.(time.Time); ok {
+			switch {
+			case l.Before(r):
+				return -1, nil
+			case l.After(r):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot compare %v with %v", a, b)
+}
+
+//contains checks if item is an element of a list,
+//a key of a map or a substring of a string
+func contains(container interface{}, item interface{}) (bool, error) {
+
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot look for %v in a string", item)
+		}
+		return strings.Contains(c, s), nil
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot look for %v in a map", item)
+		}
+		_, found := c[key]
+		return found, nil
+	}
+
+	v := reflect.ValueOf(container)
+	if container == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		return false, fmt.Errorf("cannot look for %v in %v", item, container)
+	}
+	for i := 0; i < v.Len(); i++ {
+		if equal(normalize(v.Index(i).Interface()), item) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ---- functions ----
+
+//builtins are the functions expressions can call
+var builtins = map[string]func(args []interface{}) (interface{}, error){
+	"len": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expects one argument")
+		}
+		v := reflect.ValueOf(args[0])
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			return float64(v.Len()), nil
+		}
+		return nil, fmt.Errorf("%v has no length", args[0])
+	},
+	"has": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expects one argument")
+		}
+		return args[0] != nil, nil
+	},
+	"lower":      stringFunc(strings.ToLower),
+	"upper":      stringFunc(strings.ToUpper),
+	"startsWith": stringPredicate(strings.HasPrefix),
+	"endsWith":   stringPredicate(strings.HasSuffix),
+	"matches": func(args []interface{}) (interface{}, error) {
+		s, pattern, err := twoStrings(args)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	},
+	"date": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expects one argument")
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expects a string, got %v", args[0])
+		}
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", s)
+	},
+	"days": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("expects two arguments")
+		}
+		from, fok := args[0].(time.Time)
+		to, tok := args[1].(time.Time)
+		if !fok || !tok {
+			return nil, fmt.Errorf("expects two times, got %v and %v", args[0], args[1])
+		}
+		return math.Floor(to.Sub(from).Hours() / 24), nil
+	},
+}
+
+//stringFunc adapts a string transformation to a builtin
+func stringFunc(f func(string) string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expects one argument")
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expects a string, got %v", args[0])
+		}
+		return f(s), nil
+	}
+}
+
+//stringPredicate adapts a two string predicate to a builtin
+func stringPredicate(f func(string, string) bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		a, b, err := twoStrings(args)
+		if err != nil {
+			return nil, err
+		}
+		return f(a, b), nil
+	}
+}
+
+//twoStrings extracts the two string arguments of a builtin
+func twoStrings(args []interface{}) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", errors.New("expects two arguments")
+	}
+	a, aok := args[0].(string)
+	b, bok := args[1].(string)
+	if !aok || !bok {
+		return "", "", fmt.Errorf("expects two strings, got %v and %v", args[0], args[1])
+	}
+	return a, b, nil
+}
@@ -0,0 +1,195 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---- helper types and functions ----
+type mockEntity struct {
+	start, end time.Time
+	attributes map[string]interface{}
+}
+
+func (m mockEntity) IsExistentAt(pit time.Time) bool {
+	return !m.start.After(pit) && (m.end.IsZero() || m.end.After(pit))
+}
+
+func (m mockEntity) ExistentFrom() time.Time {
+	return m.start
+}
+
+func (m mockEntity) ValidUntil() time.Time {
+	return m.end
+}
+
+func (m mockEntity) ActiveDuration() time.Duration {
+	return m.end.Sub(m.start)
+}
+
+func (m mockEntity) GetAttributeNames() []string {
+	names := make([]string, 0, len(m.attributes))
+	for name := range m.attributes {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (m mockEntity) HasAttribute(attrName string) bool {
+	_, ok := m.attributes[attrName]
+	return ok
+}
+
+func (m mockEntity) GetAttribute(attrName string) (interface{}, error) {
+	if value, ok := m.attributes[attrName]; ok {
+		return value, nil
+	}
+	return nil, errors.New("no such attribute")
+}
+
+func (m mockEntity) SetAttribute(attrName string, value interface{}) interface{} {
+	previous := m.attributes[attrName]
+	m.attributes[attrName] = value
+	return previous
+}
+
+// ------------------ Tests -------
+
+func TestEval(t *testing.T) {
+
+	env := Env{
+		"n":     3,
+		"name":  "Athens Office",
+		"tags":  []string{"hq", "sales"},
+		"attrs": map[string]interface{}{"grade": int64(4), "site": "Athens"},
+		"πόλη":  "Αθήνα",
+	}
+
+	cases := map[string]interface{}{
+		"1 + 2 * 3":                               7.0,
+		"(1 + 2) * 3":                             9.0,
+		"-n + 10 % 4":                             -1.0,
+		"n / 2":                                   1.5,
+		"'a' + \"b\"":                             "ab",
+		"n == 3 && !false":                        true,
+		"n > 5 || name == 'Athens Office'":        true,
+		"attrs.grade >= 4":                        true,
+		"attrs.site in ['Athens', 'Patras']":      true,
+		"'sales' in tags":                         true,
+		"'grade' in attrs":                        true,
+		"'Office' in name":                        true,
+		"attrs.missing == null":                   true,
+		"attrs.missing.deeper":                    nil,
+		"has(attrs.grade)":                        true,
+		"len(tags) + len(name)":                   15.0,
+		"tags[1]":                                 "sales",
+		"attrs['site']":                           "Athens",
+		"lower(name)":                             "athens office",
+		"startsWith(name, 'Ath')":                 true,
+		"matches(name, '^[A-Z][a-z]+ ')":          true,
+		"date('2020-01-02') < date('2020-02-01')": true,
+		"days(date('2020-01-02'), date('2020-02-01'))": 30.0,
+		"[1, 2] == [1, 2]": true,
+		"πόλη == 'Αθήνα'":  true,
+		"'a\\tb\\nc'":      "a\tb\nc",
+		"'it\\'s'":         "it's",
+		"'a\\\\b'":         "a\\b",
+	}
+
+	for source, expected := range cases {
+		p, err := Compile(source)
+		if err != nil {
+			t.Errorf("%s: cannot compile: %v", source, err)
+			continue
+		}
+		got, err := p.Eval(env)
+		if err != nil {
+			t.Errorf("%s: cannot evaluate: %v", source, err)
+			continue
+		}
+		if !equal(got, expected) {
+			t.Errorf("%s: expected %v, got %v", source, expected, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+
+	for _, source := range []string{"", "1 +", "(1", "'open", "a $ b", "1 2", "f(1,", "(1)(2)", "a.1"} {
+		if _, err := Compile(source); err == nil {
+			t.Errorf("%q: expected a compile error", source)
+		}
+	}
+
+	for _, source := range []string{"'a\xff'", "'a\\\xff'", "a\xff"} {
+		if _, err := Compile(source); err == nil {
+			t.Errorf("%q: expected invalid UTF-8 to be rejected", source)
+		}
+	}
+
+	// nesting up to maxDepth is accepted, whatever the
+	// precedence levels in between
+	for _, deep := range []string{
+		strings.Repeat("(", maxDepth) + "1" + strings.Repeat(")", maxDepth),
+		strings.Repeat("lower(", maxDepth) + "'A'" + strings.Repeat(")", maxDepth),
+		strings.Repeat("[", maxDepth) + "1" + strings.Repeat("]", maxDepth),
+		strings.Repeat("!", maxDepth) + "true",
+	} {
+		if _, err := Compile(deep); err != nil {
+			t.Errorf("%.10s...: unexpected error %v", deep, err)
+		}
+		if _, err := Compile("(" + deep + ")"); err == nil {
+			t.Errorf("%.10s...: expected deep nesting to be rejected", deep)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+
+	env := Env{"n": 1, "s": "x"}
+	for _, source := range []string{"unknown", "n && true", "n + s", "s < n", "n / 0", "n % 0", "nope(1)", "!n", "-s", "len(n)", "[1][3]", "n.field"} {
+		p, err := Compile(source)
+		if err != nil {
+			t.Errorf("%q: unexpected compile error %v", source, err)
+			continue
+		}
+		if _, err := p.Eval(env); err == nil {
+			t.Errorf("%q: expected an evaluation error", source)
+		}
+	}
+
+	// the right side is not evaluated when short circuiting
+	p, _ := Compile("false && unknown")
+	if v, err := p.EvalBool(env); err != nil || v {
+		t.Errorf("expected false without error, got %v, %v", v, err)
+	}
+
+	p, _ = Compile("n + 1")
+	if _, err := p.EvalBool(env); err == nil {
+		t.Error("expected an error for a non boolean outcome")
+	}
+}
+
+func TestEntityEnv(t *testing.T) {
+
+	e := mockEntity{
+		start:      time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		attributes: map[string]interface{}{"grade": 5, "site": "Patras"},
+	}
+	pit := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	p, err := Compile("active && until == null && attrs.grade > 4 && days(from, pit) >= 365")
+	if err != nil {
+		t.Fatalf("cannot compile: %v", err)
+	}
+	if ok, err := p.EvalBool(EntityEnv(e, pit)); err != nil || !ok {
+		t.Errorf("expected true, got %v, %v", ok, err)
+	}
+
+	e.end = time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	if ok, err := p.EvalBool(EntityEnv(e, pit)); err != nil || ok {
+		t.Errorf("expected false, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,134 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//tokenKind classifies the tokens of an expression
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+//token is a single lexical element of an expression
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+//operators lists the operators and punctuation, the
+//longer ones first so they are matched greedily
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"<", ">", "+", "-", "*", "/", "%", "!",
+	"(", ")", "[", "]", ",", ".",
+}
+
+//escapes maps the characters following a backslash in
+//a string literal to the characters they stand for. Any
+//other character following a backslash stands for itself
+var escapes = map[rune]rune{
+	'n': '\n',
+	't': '\t',
+	'r': '\r',
+}
+
+//tokenize splits the source of an expression into tokens.
+//The source is UTF-8 encoded and positions are byte offsets
+func tokenize(src string) ([]token, error) {
+
+	// runeAt decodes the character at the given offset
+	runeAt := func(pos int) (rune, int) {
+		return utf8.DecodeRuneInString(src[pos:])
+	}
+
+	tokens := make([]token, 0)
+	for pos := 0; pos < len(src); {
+		c, size := runeAt(pos)
+
+		switch {
+		case c == utf8.RuneError && size == 1:
+			return nil, fmt.Errorf("invalid UTF-8 at %d", pos)
+
+		case unicode.IsSpace(c):
+			pos += size
+
+		case c >= '0' && c <= '9':
+			start := pos
+			for pos < len(src) && (src[pos] >= '0' && src[pos] <= '9' || src[pos] == '.') {
+				pos++
+			}
+			n, err := strconv.ParseFloat(src[start:pos], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", src[start:pos], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:pos], value: n, pos: start})
+
+		case c == '"' || c == '\'':
+			start := pos
+			var str strings.Builder
+			pos++
+			for {
+				if pos >= len(src) {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				r, size := runeAt(pos)
+				escaped := r == '\\' && pos+size < len(src)
+				if escaped {
+					pos += size
+					r, size = runeAt(pos)
+				}
+				if r == utf8.RuneError && size == 1 {
+					return nil, fmt.Errorf("invalid UTF-8 at %d", pos)
+				}
+				pos += size
+				if !escaped && r == c {
+					break
+				}
+				if e, ok := escapes[r]; escaped && ok {
+					r = e
+				}
+				str.WriteRune(r)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[start:pos], value: str.String(), pos: start})
+
+		case c == '_' || unicode.IsLetter(c):
+			start := pos
+			for pos < len(src) {
+				r, size := runeAt(pos)
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:pos], pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[pos:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, pos)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
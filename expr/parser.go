@@ -0,0 +1,260 @@
+package expr
+
+import (
+	"fmt"
+)
+
+//maxDepth bounds the nesting of parentheses, brackets,
+//calls and unary operators in an expression, so hostile
+//input can't exhaust the stack
+const maxDepth = 64
+
+//parser is a recursive descent parser
+//over the tokens of an expression
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+//peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+//next consumes and returns the current token
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+//accept consumes the current token if it is
+//one of the given operators or keywords
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+//expect consumes the given operator or fails
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return fmt.Errorf("expected %q at %d", text, p.peek().pos)
+	}
+	return nil
+}
+
+//enter steps one level deeper in the nesting of the
+//expression, failing past maxDepth. Every successful
+//call must be paired with a call to leave
+func (p *parser) enter() error {
+	if p.depth == maxDepth {
+		return fmt.Errorf("expression nested deeper than %d at %d", maxDepth, p.peek().pos)
+	}
+	p.depth++
+	return nil
+}
+
+//leave steps back one level of nesting, see enter
+func (p *parser) leave() {
+	p.depth--
+}
+
+//binaryLevels lists the binary operators from
+//the lowest to the highest precedence
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+//parseExpression parses an expression starting at
+//the given precedence level
+func (p *parser) parseExpression(level int) (node, error) {
+
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseExpression(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept(binaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.parseExpression(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+//parseUnary parses negations and the postfix
+//expressions they apply to
+func (p *parser) parseUnary() (node, error) {
+
+	if op, ok := p.accept("!", "-"); ok {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, operand: operand}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+//parsePostfix parses member access, indexing
+//and function calls
+func (p *parser) parsePostfix() (node, error) {
+
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.peek().text == "." && p.peek().kind == tokenOperator:
+			p.next()
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name at %d", name.pos)
+			}
+			n = memberNode{object: n, name: name.text}
+
+		case p.peek().text == "[" && p.peek().kind == tokenOperator:
+			p.next()
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			index, err := p.parseExpression(0)
+			p.leave()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = indexNode{object: n, index: index}
+
+		case p.peek().text == "(" && p.peek().kind == tokenOperator:
+			v, ok := n.(variableNode)
+			if !ok {
+				return nil, fmt.Errorf("only functions can be called, at %d", p.peek().pos)
+			}
+			p.next()
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			n = callNode{name: v.name, args: args}
+
+		default:
+			return n, nil
+		}
+	}
+}
+
+//parsePrimary parses literals, variables
+//and parenthesized expressions
+func (p *parser) parsePrimary() (node, error) {
+
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return literalNode{value: t.value}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		return variableNode{name: t.text}, nil
+
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			defer p.leave()
+			n, err := p.parseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return listNode{items: items}, nil
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+//parseList parses comma separated expressions
+//up to the closing operator, the arguments of a
+//call or the items of a list
+func (p *parser) parseList(closing string) ([]node, error) {
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	items := make([]node, 0)
+	if _, ok := p.accept(closing); ok {
+		return items, nil
+	}
+
+	for {
+		item, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		if _, ok := p.accept(closing); ok {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
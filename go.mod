@@ -1,3 +1,5 @@
 module github.com/NTsiridis/orgopus
 
-go 1.14
+go 1.23
+
+require github.com/mattn/go-sqlite3 v1.14.33
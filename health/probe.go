@@ -0,0 +1,130 @@
+//Package health provides liveness and readiness probes,
+//suitable for Kubernetes, built from named checks.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+//Check is a single named health check. Run
+//returns nil when the check passes
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+//Probe runs a set of checks and reports
+//healthy only if all of them pass
+type Probe struct {
+	Checks []Check
+	// the time allowed to all the checks of
+	// a single request, no limit when 0
+	Timeout time.Duration
+}
+
+//Result is the outcome of running a probe
+type Result struct {
+	Healthy bool
+	// the error of every failed check
+	// keyed by the check name
+	Failures map[string]error
+}
+
+//Run executes all the checks of the probe
+func (p *Probe) Run(ctx context.Context) Result {
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	result := Result{Healthy: true, Failures: make(map[string]error)}
+	for _, check := range p.Checks {
+		if err := check.Run(ctx); err != nil {
+			result.Healthy = false
+			result.Failures[check.Name] = err
+		}
+	}
+
+	return result
+}
+
+//ServeHTTP runs the probe and responds with 200 when
+//healthy or 503 otherwise. The body is a JSON document
+//with the status of every check
+func (p *Probe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	result := p.Run(r.Context())
+
+	checks := make(map[string]string, len(p.Checks))
+	for _, check := range p.Checks {
+		checks[check.Name] = "ok"
+		if err, failed := result.Failures[check.Name]; failed {
+			checks[check.Name] = err.Error()
+		}
+	}
+
+	status, code := "ok", http.StatusOK
+	if !result.Healthy {
+		status, code = "failing", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+//NewServeMux returns a mux serving the liveness probe
+//at /healthz and the readiness probe at /readyz
+func NewServeMux(liveness, readiness *Probe) *http.ServeMux {
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", liveness)
+	mux.Handle("/readyz", readiness)
+
+	return mux
+}
+
+//InvariantChecker is implemented by the collections of
+//the domain package, both the plain and the concurrency
+//safe one
+type InvariantChecker interface {
+	CheckInvariants() error
+}
+
+//InvariantsCheck returns a check verifying that the interval
+//tree of the collection is valid. Probes run while the
+//collection is being written, so c should be safe for
+//concurrent use, like a ConcurrentTimeTrackedEntityCollection.
+//The check fails as soon as ctx is done, leaving the
+//verification to finish in the background
+func InvariantsCheck(name string, c InvariantChecker) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- c.CheckInvariants()
+			}()
+
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+func passing(name string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error { return nil }}
+}
+
+func failing(name string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error { return errors.New("unreachable") }}
+}
+
+func TestProbeRun(t *testing.T) {
+
+	probe := Probe{Checks: []Check{passing("storage"), failing("connector")}}
+
+	result := probe.Run(context.Background())
+	if result.Healthy {
+		t.Error("expected the probe to fail")
+	}
+	if len(result.Failures) != 1 || result.Failures["connector"] == nil {
+		t.Errorf("unexpected failures %v", result.Failures)
+	}
+}
+
+func TestServeMux(t *testing.T) {
+
+	collection := domain.EntityCollection{}
+	liveness := &Probe{Checks: []Check{InvariantsCheck("tree", &collection)}}
+	readiness := &Probe{Checks: []Check{passing("storage"), failing("connector")}}
+
+	server := httptest.NewServer(NewServeMux(liveness, readiness))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 from /healthz, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 from /readyz, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Status string
+		Checks map[string]string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("cannot decode body: %v", err)
+	}
+	if body.Status != "failing" || body.Checks["storage"] != "ok" || body.Checks["connector"] != "unreachable" {
+		t.Errorf("unexpected body %+v", body)
+	}
+}
+
+// blockingChecker completes a check once released
+type blockingChecker chan struct{}
+
+func (b blockingChecker) CheckInvariants() error {
+	<-b
+	return nil
+}
+
+func TestInvariantsCheck(t *testing.T) {
+
+	// run with -race, the collection is written while checked
+	var collection domain.ConcurrentTimeTrackedEntityCollection[domain.TimeTrackedEntity]
+	check := InvariantsCheck("tree", &collection)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 200; i++ {
+			collection.AddEntity(&domain.AttributeValue{Name: "grade", Value: i, From: start.AddDate(0, 0, i), Until: start.AddDate(0, 0, i+1)})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := check.Run(context.Background()); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stuck := make(blockingChecker)
+	defer close(stuck)
+	if err := InvariantsCheck("stuck", stuck).Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline error, got %v", err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := check.Run(cancelled); err != context.Canceled {
+		t.Errorf("expected the cancel error, got %v", err)
+	}
+}
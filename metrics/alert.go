@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NTsiridis/orgopus/clock"
+)
+
+//Condition tells how a Rule compares the
+//metric against its threshold
+type Condition int
+
+const (
+	// Above fires when the value exceeds the threshold
+	Above Condition = iota
+	// Below fires when the value drops under the threshold
+	Below
+)
+
+//String implementation of a condition
+func (c Condition) String() string {
+	if c == Below {
+		return "below"
+	}
+	return "above"
+}
+
+//Rule is an alert rule on the latest value of a metric,
+//like "turnover of unit X above 0.05"
+type Rule struct {
+	Name      string
+	Metric    string
+	Condition Condition
+	Threshold float64
+}
+
+//Check evaluates the rule against the latest point of s
+//and returns the alert to raise, if any
+func (r Rule) Check(s Series) (Alert, bool) {
+
+	if len(s) == 0 {
+		return Alert{}, false
+	}
+
+	latest := s[len(s)-1]
+	fired := latest.Value > r.Threshold
+	if r.Condition == Below {
+		fired = latest.Value < r.Threshold
+	}
+	if !fired {
+		return Alert{}, false
+	}
+
+	return Alert{Rule: r, At: latest.At, Value: latest.Value}, true
+}
+
+//Alert is raised when a Rule fires
+type Alert struct {
+	Rule  Rule
+	At    time.Time
+	Value float64
+}
+
+//String implementation of an alert
+func (a Alert) String() string {
+	return fmt.Sprintf("%s: %s is %v, %s threshold %v at %s",
+		a.Rule.Name, a.Rule.Metric, a.Value, a.Rule.Condition, a.Rule.Threshold,
+		a.At.Format("2006-01-02"))
+}
+
+//Source returns the current series of a metric
+type Source func(metric string) (Series, error)
+
+//Emitter delivers alerts to the outside world
+type Emitter interface {
+	Emit(a Alert) error
+}
+
+//ContextEmitter is an Emitter whose deliveries can be
+//cancelled. Monitor.Run hands its context to Emitters
+//implementing it, so stopping the monitor aborts the
+//deliveries in flight
+type ContextEmitter interface {
+	Emitter
+	EmitContext(ctx context.Context, a Alert) error
+}
+
+//Monitor evaluates a set of rules against the series
+//provided by Source and hands fired alerts to Emitter
+type Monitor struct {
+	Rules   []Rule
+	Source  Source
+	Emitter Emitter
+	// the clock driving Run, the package
+	// level clock.Default() when nil
+	Clock clock.Clock
+}
+
+//Evaluate checks every rule once, emits the alerts that fired
+//and returns them, see EvaluateContext
+func (m *Monitor) Evaluate() ([]Alert, error) {
+	return m.EvaluateContext(context.Background())
+}
+
+//EvaluateContext checks every rule once, emits the alerts that
+//fired and returns them. ctx is handed to a ContextEmitter.
+//Failing to fetch a metric or to emit an alert doesn't stop
+//the evaluation of the remaining rules, the first error met
+//is returned at the end
+func (m *Monitor) EvaluateContext(ctx context.Context) ([]Alert, error) {
+
+	var alerts []Alert
+	var firstErr error
+	for _, rule := range m.Rules {
+		s, err := m.Source(rule.Metric)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("rule %s: %v", rule.Name, err)
+			}
+			continue
+		}
+
+		alert, fired := rule.Check(s)
+		if !fired {
+			continue
+		}
+		alerts = append(alerts, alert)
+
+		if m.Emitter == nil {
+			continue
+		}
+		if err := m.emit(ctx, alert); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("rule %s: %v", rule.Name, err)
+		}
+	}
+
+	return alerts, firstErr
+}
+
+//emit hands the alert to the Emitter, along with
+//ctx if it is a ContextEmitter
+func (m *Monitor) emit(ctx context.Context, a Alert) error {
+
+	if emitter, ok := m.Emitter.(ContextEmitter); ok {
+		return emitter.EmitContext(ctx, a)
+	}
+
+	return m.Emitter.Emit(a)
+}
+
+//Run evaluates the rules every interval until ctx is done.
+//Errors of single evaluations are passed to onError, which
+//may be nil
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+
+	c := m.Clock
+	if c == nil {
+		c = clock.Default()
+	}
+
+	ticker := c.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			if _, err := m.EvaluateContext(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
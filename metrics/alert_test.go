@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/clock"
+)
+
+// ---- helper types and functions ----
+type collectingEmitter struct {
+	alerts []Alert
+}
+
+func (c *collectingEmitter) Emit(a Alert) error {
+	c.alerts = append(c.alerts, a)
+	return nil
+}
+
+// contextEmitter records the contexts it is given
+type contextEmitter struct {
+	collectingEmitter
+	contexts []context.Context
+}
+
+func (c *contextEmitter) EmitContext(ctx context.Context, a Alert) error {
+	c.contexts = append(c.contexts, ctx)
+	return c.Emit(a)
+}
+
+func testSource(metric string) (Series, error) {
+	switch metric {
+	case "turnover":
+		return monthlySeries(0.01, 0.07), nil
+	case "vacancies":
+		return monthlySeries(0.2, 0.05), nil
+	}
+	return nil, errors.New("unknown metric")
+}
+
+// ------------------ Tests -------
+
+func TestRuleCheck(t *testing.T) {
+
+	rule := Rule{Name: "high turnover", Metric: "turnover", Condition: Above, Threshold: 0.05}
+
+	alert, fired := rule.Check(monthlySeries(0.1, 0.06))
+	if !fired || alert.Value != 0.06 {
+		t.Errorf("expected the rule to fire on the latest value, got %v", alert)
+	}
+	if _, fired := rule.Check(monthlySeries(0.1, 0.04)); fired {
+		t.Error("expected the rule not to fire")
+	}
+
+	rule.Condition = Below
+	if _, fired := rule.Check(monthlySeries(0.1, 0.04)); !fired {
+		t.Error("expected the rule to fire")
+	}
+	if _, fired := rule.Check(Series{}); fired {
+		t.Error("expected the rule not to fire on an empty series")
+	}
+}
+
+func TestMonitorEvaluate(t *testing.T) {
+
+	emitter := &collectingEmitter{}
+	monitor := Monitor{
+		Rules: []Rule{
+			{Name: "high turnover", Metric: "turnover", Condition: Above, Threshold: 0.05},
+			{Name: "many vacancies", Metric: "vacancies", Condition: Above, Threshold: 0.1},
+			{Name: "broken", Metric: "unknown", Condition: Above, Threshold: 0},
+		},
+		Source:  testSource,
+		Emitter: emitter,
+	}
+
+	alerts, err := monitor.Evaluate()
+	if err == nil {
+		t.Error("expected an error for the unknown metric")
+	}
+	if len(alerts) != 1 || alerts[0].Rule.Name != "high turnover" {
+		t.Errorf("unexpected alerts %v", alerts)
+	}
+	if len(emitter.alerts) != 1 {
+		t.Errorf("expected 1 emitted alert, got %d", len(emitter.alerts))
+	}
+}
+
+func TestMonitorEvaluateContext(t *testing.T) {
+
+	emitter := &contextEmitter{}
+	monitor := Monitor{
+		Rules:   []Rule{{Name: "high turnover", Metric: "turnover", Condition: Above, Threshold: 0.05}},
+		Source:  testSource,
+		Emitter: emitter,
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "run")
+	if _, err := monitor.EvaluateContext(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(emitter.contexts) != 1 || emitter.contexts[0].Value(key{}) != "run" {
+		t.Errorf("expected the context to be handed to the emitter, got %v", emitter.contexts)
+	}
+	if len(emitter.alerts) != 1 {
+		t.Errorf("expected 1 emitted alert, got %d", len(emitter.alerts))
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+
+	emitter := &collectingEmitter{}
+	monitor := Monitor{
+		Rules:   []Rule{{Name: "high turnover", Metric: "turnover", Condition: Above, Threshold: 0.05}},
+		Source:  testSource,
+		Emitter: emitter,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := monitor.Run(ctx, 5*time.Millisecond, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline error, got %v", err)
+	}
+	if len(emitter.alerts) == 0 {
+		t.Error("expected the rules to be evaluated at least once")
+	}
+}
+
+func TestMonitorRunSimulated(t *testing.T) {
+
+	evaluated := make(chan struct{}, 1)
+	simulated := clock.NewSimulated(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := Monitor{
+		Rules: []Rule{{Name: "high turnover", Metric: "turnover", Condition: Above, Threshold: 0.05}},
+		Source: func(metric string) (Series, error) {
+			evaluated <- struct{}{}
+			return testSource(metric)
+		},
+		Clock: simulated,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- monitor.Run(ctx, 24*time.Hour, nil) }()
+
+	// a month of daily evaluations, one tick at a time
+	for day := 0; day < 30; day++ {
+		for {
+			simulated.Advance(24 * time.Hour)
+			select {
+			case <-evaluated:
+			case <-time.After(10 * time.Millisecond):
+				// the ticker wasn't created yet, retry
+				continue
+			}
+			break
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+//Package metrics provides computations over numeric time
+//series, like headcount or turnover sampled over time:
+//rolling averages, percentiles, month over month trends and
+//seasonality aware smoothing.
+package metrics
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//ErrEmptySeries is returned when a computation
+//needs at least one point
+var ErrEmptySeries = errors.New("series has no points")
+
+//ErrInvalidPercentile is returned when the requested
+//percentile is outside [0, 100]
+var ErrInvalidPercentile = errors.New("percentile must be between 0 and 100")
+
+//Point is a single sample of a metric
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+//Series is a sequence of samples of a metric. The
+//computations expect it ordered by time, see Sorted
+type Series []Point
+
+//Sorted returns a copy of the series ordered by time
+func (s Series) Sorted() Series {
+
+	sorted := make(Series, len(s))
+	copy(sorted, s)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].At.Before(sorted[j].At)
+	})
+
+	return sorted
+}
+
+//Values returns the values of the series in order
+func (s Series) Values() []float64 {
+
+	values := make([]float64, len(s))
+	for i, p := range s {
+		values[i] = p.Value
+	}
+
+	return values
+}
+
+//RollingAverage returns a series where every point is the
+//average of the point itself and up to window-1 points
+//before it. A window smaller than 1 is treated as 1
+func (s Series) RollingAverage(window int) Series {
+
+	if window < 1 {
+		window = 1
+	}
+
+	averaged := make(Series, len(s))
+	var sum float64
+	for i, p := range s {
+		sum += p.Value
+		if i >= window {
+			sum -= s[i-window].Value
+		}
+
+		size := window
+		if i+1 < window {
+			size = i + 1
+		}
+		averaged[i] = Point{At: p.At, Value: sum / float64(size)}
+	}
+
+	return averaged
+}
+
+//Percentile returns the p-th percentile (0 to 100) of the
+//values of the series, interpolating linearly between the
+//closest ranks
+func (s Series) Percentile(p float64) (float64, error) {
+
+	if len(s) == 0 {
+		return 0, ErrEmptySeries
+	}
+	if p < 0 || p > 100 {
+		return 0, ErrInvalidPercentile
+	}
+
+	values := s.Values()
+	sort.Float64s(values)
+
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+
+	return values[lower] + fraction*(values[upper]-values[lower]), nil
+}
+
+//Monthly buckets the series per calendar month, in the
+//location of its points, and returns one point per month
+//holding the average of the values sampled in it. Points
+//are placed at the first instant of their month
+func (s Series) Monthly() Series {
+	return s.Bucketed(func(pit time.Time) domain.Period {
+		return domain.Month(pit.Year(), pit.Month(), pit.Location())
+	})
+}
+
+//Bucketed groups consecutive points falling in the same period,
+//as returned by periodOf, and returns one point per period holding
+//the average of its values, placed at the start of the period.
+//Passing the PeriodOf or QuarterOf of a domain.FiscalCalendar
+//buckets the series along fiscal periods
+func (s Series) Bucketed(periodOf func(time.Time) domain.Period) Series {
+
+	bucketed := make(Series, 0)
+	var current domain.Period
+	var sum float64
+	var count int
+	for _, p := range s {
+		if count > 0 && !current.Contains(p.At) {
+			bucketed = append(bucketed, Point{At: current.Start(), Value: sum / float64(count)})
+			sum, count = 0, 0
+		}
+		if count == 0 {
+			current = periodOf(p.At)
+		}
+		sum += p.Value
+		count++
+	}
+	if count > 0 {
+		bucketed = append(bucketed, Point{At: current.Start(), Value: sum / float64(count)})
+	}
+
+	return bucketed
+}
+
+//MonthOverMonth returns the relative change of the monthly
+//averages of the series, one point per month after the first.
+//A change of 0.05 means an increase of 5%. Months following
+//a month with a zero value are skipped
+func (s Series) MonthOverMonth() Series {
+
+	monthly := s.Monthly()
+	trend := make(Series, 0, len(monthly))
+	for i := 1; i < len(monthly); i++ {
+		previous := monthly[i-1].Value
+		if previous == 0 {
+			continue
+		}
+		trend = append(trend, Point{
+			At:    monthly[i].At,
+			Value: (monthly[i].Value - previous) / previous,
+		})
+	}
+
+	return trend
+}
+
+//SmoothingOptions configures Smooth
+type SmoothingOptions struct {
+	// the number of points averaged together
+	Window int
+	// the length of a season in points (12 for
+	// monthly data with a yearly cycle), 0 when
+	// the series has no seasonality
+	SeasonalPeriod int
+}
+
+//Smooth returns a smoothed version of the series. When a
+//seasonal period is given, the seasonal component is removed
+//first so recurring peaks (like yearly hiring waves) are not
+//mistaken for a trend, and the rolling average is applied on
+//the seasonally adjusted values
+func (s Series) Smooth(opts SmoothingOptions) Series {
+
+	adjusted := s
+	if opts.SeasonalPeriod > 1 {
+		adjusted = s.SeasonallyAdjusted(opts.SeasonalPeriod)
+	}
+
+	return adjusted.RollingAverage(opts.Window)
+}
+
+//SeasonallyAdjusted removes an additive seasonal component of
+//the given period from the series. The component of every
+//position within the season is the average deviation of the
+//points at that position from the overall mean. Series shorter
+//than two seasons are returned unchanged
+func (s Series) SeasonallyAdjusted(period int) Series {
+
+	if period < 2 || len(s) < 2*period {
+		adjusted := make(Series, len(s))
+		copy(adjusted, s)
+		return adjusted
+	}
+
+	var mean float64
+	for _, p := range s {
+		mean += p.Value
+	}
+	mean /= float64(len(s))
+
+	seasonal := make([]float64, period)
+	counts := make([]int, period)
+	for i, p := range s {
+		seasonal[i%period] += p.Value - mean
+		counts[i%period]++
+	}
+	for i := range seasonal {
+		seasonal[i] /= float64(counts[i])
+	}
+
+	adjusted := make(Series, len(s))
+	for i, p := range s {
+		adjusted[i] = Point{At: p.At, Value: p.Value - seasonal[i%period]}
+	}
+
+	return adjusted
+}
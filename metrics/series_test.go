@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+// ---- helper types and functions ----
+func monthlySeries(values ...float64) Series {
+	s := make(Series, len(values))
+	for i, v := range values {
+		s[i] = Point{
+			At:    time.Date(2020, time.Month(i+1), 15, 0, 0, 0, 0, time.UTC),
+			Value: v,
+		}
+	}
+	return s
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// ------------------ Tests -------
+
+func TestRollingAverage(t *testing.T) {
+
+	averaged := monthlySeries(1, 2, 3, 4, 5).RollingAverage(3)
+	expected := []float64{1, 1.5, 2, 3, 4}
+	for i, v := range averaged.Values() {
+		if !almostEqual(v, expected[i]) {
+			t.Errorf("point %d: expected %v, got %v", i, expected[i], v)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+
+	s := monthlySeries(15, 20, 35, 40, 50)
+
+	if p, err := s.Percentile(50); err != nil || p != 35 {
+		t.Errorf("expected median 35, got %v (%v)", p, err)
+	}
+	if p, _ := s.Percentile(40); !almostEqual(p, 29) {
+		t.Errorf("expected 29, got %v", p)
+	}
+	if _, err := s.Percentile(101); err != ErrInvalidPercentile {
+		t.Errorf("expected ErrInvalidPercentile, got %v", err)
+	}
+	if _, err := (Series{}).Percentile(50); err != ErrEmptySeries {
+		t.Errorf("expected ErrEmptySeries, got %v", err)
+	}
+}
+
+func TestMonthOverMonth(t *testing.T) {
+
+	s := Series{
+		{At: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Value: 100},
+		{At: time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC), Value: 100},
+		{At: time.Date(2020, 2, 10, 0, 0, 0, 0, time.UTC), Value: 110},
+		{At: time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC), Value: 99},
+	}
+
+	trend := s.MonthOverMonth()
+	if len(trend) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(trend))
+	}
+	if !almostEqual(trend[0].Value, 0.1) || !almostEqual(trend[1].Value, -0.1) {
+		t.Errorf("unexpected trend %v", trend.Values())
+	}
+	if trend[0].At != time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected month %v", trend[0].At)
+	}
+}
+
+func TestSmoothRemovesSeasonality(t *testing.T) {
+
+	// a flat series with a peak every fourth point
+	s := monthlySeries(10, 10, 10, 18, 10, 10, 10, 18, 10, 10, 10, 18)
+
+	adjusted := s.Smooth(SmoothingOptions{Window: 1, SeasonalPeriod: 4})
+	for i, v := range adjusted.Values() {
+		if !almostEqual(v, 12) {
+			t.Errorf("point %d: expected 12, got %v", i, v)
+		}
+	}
+}
+
+func TestBucketedByFiscalQuarter(t *testing.T) {
+
+	calendar := domain.FiscalCalendar{StartMonth: time.April}
+
+	bucketed := monthlySeries(1, 2, 3, 4, 5, 6).Bucketed(calendar.QuarterOf)
+	if len(bucketed) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(bucketed))
+	}
+
+	expected := []float64{2, 5}
+	for i, v := range bucketed.Values() {
+		if !almostEqual(v, expected[i]) {
+			t.Errorf("bucket %d: expected %v, got %v", i, expected[i], v)
+		}
+	}
+	if bucketed[1].At != time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected bucket start %v", bucketed[1].At)
+	}
+}
@@ -0,0 +1,203 @@
+//Package notify delivers alerts to chat tools, like Slack and
+//Microsoft Teams, through their incoming webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/NTsiridis/orgopus/metrics"
+)
+
+//Kind is the chat tool a channel posts to
+type Kind int
+
+const (
+	// Slack incoming webhook
+	Slack Kind = iota
+	// Teams incoming webhook (legacy MessageCard)
+	Teams
+)
+
+//DefaultTemplate is used for channels that
+//don't define their own template
+var DefaultTemplate = template.Must(template.New("alert").Parse(
+	"{{.Rule.Name}}: {{.Rule.Metric}} is {{.Value}} ({{.Rule.Condition}} {{.Rule.Threshold}}) on {{.At.Format \"2006-01-02\"}}"))
+
+//Channel is a destination for notifications
+type Channel struct {
+	Name       string
+	Kind       Kind
+	WebhookURL string
+	// the template rendering an alert, it is
+	// executed with the metrics.Alert as data.
+	// DefaultTemplate is used when nil
+	Template *template.Template
+}
+
+//Route sends the alerts of a metric to a channel
+type Route struct {
+	// the metric to match, empty matches every metric
+	Metric string
+	// the name of the channel to send to
+	Channel string
+}
+
+//DefaultTimeout bounds the calls to the webhooks of
+//Notifiers that are not given a Client
+const DefaultTimeout = 10 * time.Second
+
+//defaultClient calls the webhooks of Notifiers
+//that are not given a Client
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+//Notifier posts alerts to the channels they are routed
+//to. It implements metrics.Emitter
+type Notifier struct {
+	Channels []Channel
+	Routes   []Route
+	// the client used to call the webhooks, a client
+	// timing out after DefaultTimeout when nil
+	Client *http.Client
+}
+
+//Emit renders the alert and posts it to every channel
+//it is routed to, see EmitContext
+func (n *Notifier) Emit(a metrics.Alert) error {
+	return n.EmitContext(context.Background(), a)
+}
+
+//EmitContext renders the alert and posts it to every channel
+//it is routed to, aborting the posts in flight once ctx is
+//done. It implements metrics.ContextEmitter. An alert routed
+//twice to the same channel is only posted once. A channel
+//failing doesn't stop the alert from being posted to the
+//rest, the errors of all the failed channels are returned
+//joined. Errors name the channel but never its webhook URL,
+//which holds the secret of the webhook
+func (n *Notifier) EmitContext(ctx context.Context, a metrics.Alert) error {
+
+	var errs []error
+	posted := make(map[string]bool)
+	for _, route := range n.Routes {
+		if (route.Metric != "" && route.Metric != a.Rule.Metric) || posted[route.Channel] {
+			continue
+		}
+		posted[route.Channel] = true
+
+		channel, ok := n.channel(route.Channel)
+		if !ok {
+			errs = append(errs, fmt.Errorf("route to unknown channel %s", route.Channel))
+			continue
+		}
+		if err := n.post(ctx, channel, a); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channel.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+//channel looks up a channel by its name
+func (n *Notifier) channel(name string) (Channel, bool) {
+	for _, c := range n.Channels {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Channel{}, false
+}
+
+//post renders the alert for the channel and calls its webhook
+func (n *Notifier) post(ctx context.Context, c Channel, a metrics.Alert) error {
+
+	text, err := Render(c, a)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload(c.Kind, text))
+	if err != nil {
+		return err
+	}
+
+	client := n.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return withoutURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer func() {
+		// drained so the connection can be reused
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+//withoutURL strips the URL from the errors of the http
+//package, as the URL of a webhook is its secret
+func withoutURL(err error) error {
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s webhook: %w", urlErr.Op, urlErr.Err)
+	}
+
+	return err
+}
+
+//Render returns the text of the alert as it would
+//be posted to the channel
+func Render(c Channel, a metrics.Alert) (string, error) {
+
+	tmpl := c.Template
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+
+	var str strings.Builder
+	if err := tmpl.Execute(&str, a); err != nil {
+		return "", err
+	}
+
+	return str.String(), nil
+}
+
+//payload builds the webhook message for the kind of channel
+func payload(kind Kind, text string) interface{} {
+
+	if kind == Teams {
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  text,
+			"text":     text,
+		}
+	}
+
+	return map[string]string{"text": text}
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/NTsiridis/orgopus/metrics"
+)
+
+// ---- helper types and functions ----
+type received struct {
+	path    string
+	payload map[string]string
+}
+
+func newWebhookServer(t *testing.T, got *[]received) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("cannot decode payload: %v", err)
+		}
+		*got = append(*got, received{path: r.URL.Path, payload: p})
+	}))
+}
+
+func testAlert(metric string) metrics.Alert {
+	return metrics.Alert{
+		Rule:  metrics.Rule{Name: "high " + metric, Metric: metric, Condition: metrics.Above, Threshold: 0.05},
+		At:    time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		Value: 0.07,
+	}
+}
+
+// ------------------ Tests -------
+
+func TestRender(t *testing.T) {
+
+	text, err := Render(Channel{}, testAlert("turnover"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "high turnover: turnover is 0.07 (above 0.05) on 2020-03-01"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+
+	custom := Channel{Template: template.Must(template.New("t").Parse("{{.Rule.Metric}}!"))}
+	if text, _ := Render(custom, testAlert("turnover")); text != "turnover!" {
+		t.Errorf("unexpected text %q", text)
+	}
+}
+
+func TestNotifierRoutes(t *testing.T) {
+
+	var got []received
+	server := newWebhookServer(t, &got)
+	defer server.Close()
+
+	notifier := Notifier{
+		Channels: []Channel{
+			{Name: "hr", Kind: Slack, WebhookURL: server.URL + "/slack"},
+			{Name: "leadership", Kind: Teams, WebhookURL: server.URL + "/teams"},
+		},
+		Routes: []Route{
+			{Channel: "hr"},
+			{Metric: "turnover", Channel: "leadership"},
+			{Metric: "turnover", Channel: "hr"},
+		},
+	}
+
+	if err := notifier.Emit(testAlert("vacancies")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := notifier.Emit(testAlert("turnover")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 posts, got %d", len(got))
+	}
+	if got[0].path != "/slack" || got[0].payload["text"] == "" {
+		t.Errorf("unexpected slack post %+v", got[0])
+	}
+	if got[2].path != "/teams" || got[2].payload["@type"] != "MessageCard" {
+		t.Errorf("unexpected teams post %+v", got[2])
+	}
+}
+
+func TestNotifierFailures(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	notifier := Notifier{
+		Channels: []Channel{{Name: "hr", WebhookURL: server.URL}},
+		Routes:   []Route{{Channel: "hr"}},
+	}
+	if err := notifier.Emit(testAlert("turnover")); err == nil {
+		t.Error("expected an error for a failing webhook")
+	}
+
+	notifier.Routes = []Route{{Channel: "missing"}}
+	if err := notifier.Emit(testAlert("turnover")); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+}
+
+func TestNotifierPostsPastFailures(t *testing.T) {
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	// never answers within the timeout of the client
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+	var got []received
+	server := newWebhookServer(t, &got)
+	defer server.Close()
+
+	notifier := Notifier{
+		Channels: []Channel{
+			{Name: "failing", WebhookURL: failing.URL},
+			{Name: "hanging", WebhookURL: hanging.URL},
+			{Name: "hr", WebhookURL: server.URL},
+		},
+		Routes: []Route{{Channel: "failing"}, {Channel: "missing"}, {Channel: "hanging"}, {Channel: "hr"}},
+		Client: &http.Client{Timeout: 50 * time.Millisecond},
+	}
+
+	err := notifier.Emit(testAlert("turnover"))
+	for _, expected := range []string{"channel failing", "unknown channel missing", "channel hanging"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to be reported in %v", expected, err)
+		}
+	}
+	if len(got) != 1 {
+		t.Errorf("expected the alert to reach the working channel, got %d posts", len(got))
+	}
+	if defaultClient.Timeout != DefaultTimeout {
+		t.Errorf("expected the default client to time out, got %v", defaultClient.Timeout)
+	}
+}
+
+func TestNotifierHidesWebhookURL(t *testing.T) {
+
+	// a closed server refuses the connection
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	secret := "/services/T000/B000/SECRET"
+
+	notifier := Notifier{
+		Channels: []Channel{
+			{Name: "refused", WebhookURL: closed.URL + secret},
+			{Name: "malformed", WebhookURL: "http://[::1" + secret},
+		},
+		Routes: []Route{{Channel: "refused"}, {Channel: "malformed"}},
+	}
+
+	err := notifier.Emit(testAlert("turnover"))
+	if err == nil {
+		t.Fatal("expected the posts to fail")
+	}
+	if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("expected the webhook URL to be left out of %q", err)
+	}
+	for _, expected := range []string{"channel refused", "channel malformed"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to be reported in %v", expected, err)
+		}
+	}
+}
+
+func TestNotifierCancel(t *testing.T) {
+
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	notifier := Notifier{
+		Channels: []Channel{{Name: "hanging", WebhookURL: hanging.URL}},
+		Routes:   []Route{{Channel: "hanging"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := notifier.EmitContext(ctx, testAlert("turnover"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the post to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > DefaultTimeout/2 {
+		t.Errorf("expected the post to be aborted on cancel, took %v", elapsed)
+	}
+}
@@ -0,0 +1,102 @@
+//Package signing adds detached Ed25519 signatures to export
+//and snapshot artifacts, so consumers can verify that an org
+//extract wasn't modified in transit.
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//ErrInvalidSignature is returned when a signature doesn't
+//match the artifact or the key it is verified with
+var ErrInvalidSignature = errors.New("invalid signature")
+
+//ErrUnknownKey is returned when verifying a signature made
+//with a key that isn't among the trusted ones
+var ErrUnknownKey = errors.New("signature made with an unknown key")
+
+//Signature is a detached signature of an artifact
+type Signature struct {
+	// identifies the public key that verifies the signature
+	KeyID string
+	Value []byte
+}
+
+//KeyID returns the identifier of a public key, the
+//first 8 bytes of its SHA-256 hash in hex
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+//Sign returns the detached signature of artifact
+func Sign(artifact []byte, key ed25519.PrivateKey) Signature {
+	return Signature{
+		KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+		Value: ed25519.Sign(key, artifact),
+	}
+}
+
+//Verify checks the signature of artifact against the trusted
+//public keys, picking the key by the id of the signature
+func Verify(artifact []byte, sig Signature, trusted ...ed25519.PublicKey) error {
+
+	for _, pub := range trusted {
+		if KeyID(pub) != sig.KeyID {
+			continue
+		}
+		if !ed25519.Verify(pub, artifact, sig.Value) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return ErrUnknownKey
+}
+
+//SignCollection signs the canonical rendering of the
+//collection (see WriteCanonical) and returns the rendering
+//along with its signature
+func SignCollection[T domain.TimeTrackedEntity](c *domain.TimeTrackedEntityCollection[T], key ed25519.PrivateKey) ([]byte, Signature, error) {
+
+	var buf bytes.Buffer
+	if err := c.WriteCanonical(&buf); err != nil {
+		return nil, Signature{}, err
+	}
+
+	return buf.Bytes(), Sign(buf.Bytes(), key), nil
+}
+
+//String encodes the signature as "ed25519:<key id>:<base64 value>",
+//the format of detached signature files
+func (s Signature) String() string {
+	return "ed25519:" + s.KeyID + ":" + base64.StdEncoding.EncodeToString(s.Value)
+}
+
+//ParseSignature decodes a signature encoded by String
+func ParseSignature(encoded string) (Signature, error) {
+
+	parts := strings.Split(strings.TrimSpace(encoded), ":")
+	if len(parts) != 3 || parts[0] != "ed25519" {
+		return Signature{}, fmt.Errorf("malformed signature %q", encoded)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Signature{}, fmt.Errorf("malformed signature value: %v", err)
+	}
+	if len(value) != ed25519.SignatureSize {
+		return Signature{}, fmt.Errorf("signature should be %d bytes, got %d", ed25519.SignatureSize, len(value))
+	}
+
+	return Signature{KeyID: parts[1], Value: value}, nil
+}
@@ -0,0 +1,98 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+// ---- helper types and functions ----
+type mockEntity struct {
+	start time.Time
+}
+
+func (m mockEntity) IsExistentAt(pit time.Time) bool {
+	return !m.start.After(pit)
+}
+
+func (m mockEntity) ExistentFrom() time.Time {
+	return m.start
+}
+
+func (m mockEntity) ValidUntil() time.Time {
+	return domain.NilTime()
+}
+
+func (m mockEntity) ActiveDuration() time.Duration {
+	return 0
+}
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	return pub, priv
+}
+
+// ------------------ Tests -------
+
+func TestSignAndVerify(t *testing.T) {
+
+	pub, priv := generateKey(t)
+	otherPub, _ := generateKey(t)
+
+	artifact := []byte("org extract")
+	sig := Sign(artifact, priv)
+
+	if err := Verify(artifact, sig, otherPub, pub); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Verify([]byte("tampered extract"), sig, pub); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := Verify(artifact, sig, otherPub); err != ErrUnknownKey {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+
+	_, priv := generateKey(t)
+	sig := Sign([]byte("org extract"), priv)
+
+	parsed, err := ParseSignature(sig.String() + "\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.KeyID != sig.KeyID || string(parsed.Value) != string(sig.Value) {
+		t.Errorf("signature didn't round trip: %v != %v", parsed, sig)
+	}
+
+	for _, malformed := range []string{"", "rsa:abc:def", "ed25519:abc:!!", "ed25519:abc:YWJj"} {
+		if _, err := ParseSignature(malformed); err == nil {
+			t.Errorf("%q: expected an error", malformed)
+		}
+	}
+}
+
+func TestSignCollection(t *testing.T) {
+
+	pub, priv := generateKey(t)
+
+	c := domain.EntityCollection{}
+	c.AddEntity(mockEntity{start: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)})
+
+	artifact, sig, err := SignCollection(&c, priv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(artifact) == 0 {
+		t.Error("expected a canonical rendering")
+	}
+	if err := Verify(artifact, sig, pub); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
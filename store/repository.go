@@ -0,0 +1,34 @@
+//Package store persists time tracked entities to durable
+//storage, so collections can be hydrated from it instead of
+//living only in memory.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//Repository stores entities of type T. Entities are
+//identified by the ID their codec assigns to them
+type Repository[T domain.TimeTrackedEntity] interface {
+
+	//Save stores the entities, replacing the
+	//stored ones that have the same IDs
+	Save(ctx context.Context, entities ...T) error
+
+	//Load returns all the stored entities
+	//in a new collection
+	Load(ctx context.Context) (*domain.TimeTrackedEntityCollection[T], error)
+
+	//FindByInterval returns the stored entities that exist
+	//at some pit of [from, to), ordered by their start. A
+	//zero to means the search is open ended
+	FindByInterval(ctx context.Context, from time.Time, to time.Time) ([]T, error)
+
+	//FindByAttribute returns the stored entities whose
+	//attribute attrName has the given value, ordered
+	//by their start
+	FindByAttribute(ctx context.Context, attrName string, value interface{}) ([]T, error)
+}
@@ -0,0 +1,433 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+//Schema creates the tables used by SQLRepository. It is
+//valid for both SQLite and PostgreSQL. Pits are stored as
+//nanoseconds since the Unix epoch, so they must fall between
+//the years 1678 and 2262, and a NULL valid_until stands for
+//an open ended entity. Attribute values are stored as JSON.
+//The name, links and attribute history of a record, if any,
+//are stored as a JSON document in entity_details
+const Schema = `
+CREATE TABLE IF NOT EXISTS entities (
+	id          TEXT PRIMARY KEY,
+	kind        TEXT NOT NULL,
+	valid_from  BIGINT NOT NULL,
+	valid_until BIGINT
+);
+CREATE INDEX IF NOT EXISTS entities_interval ON entities (valid_from, valid_until);
+CREATE TABLE IF NOT EXISTS entity_attributes (
+	entity_id TEXT NOT NULL REFERENCES entities (id) ON DELETE CASCADE,
+	name      TEXT NOT NULL,
+	value     TEXT NOT NULL,
+	PRIMARY KEY (entity_id, name)
+);
+CREATE INDEX IF NOT EXISTS entity_attributes_value ON entity_attributes (name, value);
+CREATE TABLE IF NOT EXISTS entity_details (
+	entity_id TEXT PRIMARY KEY REFERENCES entities (id) ON DELETE CASCADE,
+	document  TEXT NOT NULL
+);
+`
+
+//ErrPitOutOfRange is returned when saving an entity whose
+//interval doesn't fit the nanoseconds stored by Schema
+var ErrPitOutOfRange = errors.New("pit outside the years 1678 to 2262")
+
+//The pits that can be stored as nanoseconds since the Unix epoch
+var (
+	minPit = time.Unix(0, math.MinInt64)
+	maxPit = time.Unix(0, math.MaxInt64)
+)
+
+//Dialect is the SQL flavour of the database
+type Dialect int
+
+const (
+	// SQLite uses ? placeholders
+	SQLite Dialect = iota
+	// Postgres uses $1, $2... placeholders
+	Postgres
+)
+
+//SQLRepository is a Repository backed by a database/sql
+//database holding the tables of Schema. The driver is
+//registered by the application. Attribute values go through
+//JSON and are decoded with domain.DecodeAttribute, so integral
+//numbers come back as ints, other numbers as float64 and
+//times as strings, which the codec may convert back
+type SQLRepository[T domain.TimeTrackedEntity] struct {
+	db      *sql.DB
+	dialect Dialect
+	codec   domain.EntityCodec[T]
+}
+
+var _ Repository[domain.TimeTrackedEntity] = (*SQLRepository[domain.TimeTrackedEntity])(nil)
+
+//NewSQLRepository creates a repository storing entities in
+//db, converting them with codec
+func NewSQLRepository[T domain.TimeTrackedEntity](db *sql.DB, dialect Dialect, codec domain.EntityCodec[T]) *SQLRepository[T] {
+	return &SQLRepository[T]{db: db, dialect: dialect, codec: codec}
+}
+
+//CreateSchema creates the tables of Schema, if they don't exist
+func (r *SQLRepository[T]) CreateSchema(ctx context.Context) error {
+
+	for _, statement := range strings.Split(Schema, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := r.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Save stores the entities in a single transaction,
+//replacing the stored ones that have the same IDs. Entities
+//existing outside the years 1678 to 2262 can't be stored,
+//ErrPitOutOfRange is returned and none of them is saved
+func (r *SQLRepository[T]) Save(ctx context.Context, entities ...T) error {
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// a no-op once the transaction is committed
+	defer tx.Rollback()
+
+	for _, e := range entities {
+		record, err := r.codec.Encode(e)
+		if err != nil {
+			return err
+		}
+		if err := r.saveRecord(ctx, tx, record); err != nil {
+			return fmt.Errorf("entity %s: %w", record.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+//statement is a query along with its arguments
+type statement struct {
+	query string
+	args  []interface{}
+}
+
+//saveRecord replaces the rows of record within tx
+func (r *SQLRepository[T]) saveRecord(ctx context.Context, tx *sql.Tx, record domain.EntityRecord) error {
+
+	from, err := nanos(record.From)
+	if err != nil {
+		return err
+	}
+	var until interface{}
+	if !record.Until.IsZero() {
+		if until, err = nanos(record.Until); err != nil {
+			return err
+		}
+	}
+
+	statements := []statement{
+		{"DELETE FROM entity_attributes WHERE entity_id = ?", []interface{}{record.ID}},
+		{"DELETE FROM entity_details WHERE entity_id = ?", []interface{}{record.ID}},
+		{"DELETE FROM entities WHERE id = ?", []interface{}{record.ID}},
+		{"INSERT INTO entities (id, kind, valid_from, valid_until) VALUES (?, ?, ?, ?)",
+			[]interface{}{record.ID, record.Kind, from, until}},
+	}
+	for _, name := range sortedNames(record.Attributes) {
+		encoded, err := json.Marshal(record.Attributes[name])
+		if err != nil {
+			return fmt.Errorf("attribute %s: %v", name, err)
+		}
+		statements = append(statements, statement{
+			"INSERT INTO entity_attributes (entity_id, name, value) VALUES (?, ?, ?)",
+			[]interface{}{record.ID, name, string(encoded)},
+		})
+	}
+
+	if record.Name != "" || len(record.Links) > 0 || len(record.History) > 0 {
+		document, err := json.Marshal(domain.EntityRecord{ID: record.ID, Name: record.Name, Links: record.Links, History: record.History})
+		if err != nil {
+			return err
+		}
+		statements = append(statements, statement{
+			"INSERT INTO entity_details (entity_id, document) VALUES (?, ?)",
+			[]interface{}{record.ID, string(document)},
+		})
+	}
+
+	for _, s := range statements {
+		if _, err := tx.ExecContext(ctx, r.rebind(s.query), s.args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Load returns all the stored entities in a new
+//collection, built balanced in a single pass
+func (r *SQLRepository[T]) Load(ctx context.Context) (*domain.TimeTrackedEntityCollection[T], error) {
+
+	entities, err := r.find(ctx, "1 = 1")
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewCollectionFromEntities(entities), nil
+}
+
+//FindByInterval returns the stored entities that exist
+//at some pit of [from, to), ordered by their start
+func (r *SQLRepository[T]) FindByInterval(ctx context.Context, from time.Time, to time.Time) ([]T, error) {
+
+	if to.IsZero() {
+		return r.find(ctx, "(valid_until IS NULL OR valid_until > ?)", clampedNanos(from))
+	}
+
+	return r.find(ctx, "valid_from < ? AND (valid_until IS NULL OR valid_until > ?)", clampedNanos(to), clampedNanos(from))
+}
+
+//FindByAttribute returns the stored entities whose
+//attribute attrName has the given value, ordered by
+//their start. Values are compared in their JSON form
+func (r *SQLRepository[T]) FindByAttribute(ctx context.Context, attrName string, value interface{}) ([]T, error) {
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.find(ctx, "id IN (SELECT entity_id FROM entity_attributes WHERE name = ? AND value = ?)", attrName, string(encoded))
+}
+
+//find decodes the entities matching where, a condition
+//on the columns of the entities table. The entities and
+//their attributes are read in a single read only
+//transaction, so entities saved meanwhile are either
+//read whole or not at all
+func (r *SQLRepository[T]) find(ctx context.Context, where string, args ...interface{}) ([]T, error) {
+
+	tx, err := r.db.BeginTx(ctx, r.readOptions())
+	if err != nil {
+		return nil, err
+	}
+	// nothing was written, so nothing is lost
+	defer tx.Rollback()
+
+	records, order, err := r.queryRecords(ctx, tx, where, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.queryAttributes(ctx, tx, where, args, records); err != nil {
+		return nil, err
+	}
+	if err := r.queryDetails(ctx, tx, where, args, records); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	entities := make([]T, 0, len(order))
+	for _, id := range order {
+		e, err := r.codec.Decode(*records[id])
+		if err != nil {
+			return nil, fmt.Errorf("entity %s: %v", id, err)
+		}
+		entities = append(entities, e)
+	}
+
+	return entities, nil
+}
+
+//queryRecords reads the entities matching where and
+//returns them keyed by ID, along with the IDs in start order
+func (r *SQLRepository[T]) queryRecords(ctx context.Context, tx *sql.Tx, where string, args []interface{}) (map[string]*domain.EntityRecord, []string, error) {
+
+	rows, err := tx.QueryContext(ctx, r.rebind(
+		"SELECT id, kind, valid_from, valid_until FROM entities WHERE "+where+" ORDER BY valid_from, id"), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[string]*domain.EntityRecord)
+	var order []string
+	for rows.Next() {
+		var record domain.EntityRecord
+		var from int64
+		var until sql.NullInt64
+		if err := rows.Scan(&record.ID, &record.Kind, &from, &until); err != nil {
+			return nil, nil, err
+		}
+		record.From = time.Unix(0, from).UTC()
+		if until.Valid {
+			record.Until = time.Unix(0, until.Int64).UTC()
+		}
+		records[record.ID] = &record
+		order = append(order, record.ID)
+	}
+
+	return records, order, rows.Err()
+}
+
+//queryAttributes fills in the attributes of records,
+//which were read with the same where condition
+func (r *SQLRepository[T]) queryAttributes(ctx context.Context, tx *sql.Tx, where string, args []interface{}, records map[string]*domain.EntityRecord) error {
+
+	rows, err := tx.QueryContext(ctx, r.rebind(
+		"SELECT entity_id, name, value FROM entity_attributes WHERE entity_id IN (SELECT id FROM entities WHERE "+where+")"), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name, encoded string
+		if err := rows.Scan(&id, &name, &encoded); err != nil {
+			return err
+		}
+		record, ok := records[id]
+		if !ok {
+			// can't be, unless the database
+			// ignores the isolation level
+			continue
+		}
+		value, err := domain.DecodeAttribute([]byte(encoded))
+		if err != nil {
+			return fmt.Errorf("entity %s, attribute %s: %v", id, name, err)
+		}
+		if record.Attributes == nil {
+			record.Attributes = make(map[string]interface{})
+		}
+		record.Attributes[name] = value
+	}
+
+	return rows.Err()
+}
+
+//queryDetails fills in the name, links and history of
+//records, which were read with the same where condition
+func (r *SQLRepository[T]) queryDetails(ctx context.Context, tx *sql.Tx, where string, args []interface{}, records map[string]*domain.EntityRecord) error {
+
+	rows, err := tx.QueryContext(ctx, r.rebind(
+		"SELECT entity_id, document FROM entity_details WHERE entity_id IN (SELECT id FROM entities WHERE "+where+")"), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, document string
+		if err := rows.Scan(&id, &document); err != nil {
+			return err
+		}
+		record, ok := records[id]
+		if !ok {
+			// can't be, unless the database
+			// ignores the isolation level
+			continue
+		}
+		var details domain.EntityRecord
+		if err := json.Unmarshal([]byte(document), &details); err != nil {
+			return fmt.Errorf("entity %s: %v", id, err)
+		}
+		record.Name, record.Links, record.History = details.Name, details.Links, details.History
+	}
+
+	return rows.Err()
+}
+
+//readOptions returns the options of the transactions
+//reading entities. PostgreSQL needs repeatable read for
+//the statements of a transaction to see the same data,
+//SQLite transactions are serializable anyway
+func (r *SQLRepository[T]) readOptions() *sql.TxOptions {
+
+	options := &sql.TxOptions{ReadOnly: true}
+	if r.dialect == Postgres {
+		options.Isolation = sql.LevelRepeatableRead
+	}
+
+	return options
+}
+
+//nanos converts pit to the nanoseconds since the Unix epoch
+//stored by Schema, or returns ErrPitOutOfRange if they
+//don't fit in an int64
+func nanos(pit time.Time) (int64, error) {
+
+	if pit.Before(minPit) || pit.After(maxPit) {
+		return 0, fmt.Errorf("%w: %s", ErrPitOutOfRange, pit.UTC().Format(time.RFC3339))
+	}
+
+	return pit.UnixNano(), nil
+}
+
+//clampedNanos converts the bound of a search to the
+//nanoseconds stored by Schema, clamping it to the pits
+//that can be stored
+func clampedNanos(pit time.Time) int64 {
+
+	switch {
+	case pit.Before(minPit):
+		return math.MinInt64
+	case pit.After(maxPit):
+		return math.MaxInt64
+	}
+
+	return pit.UnixNano()
+}
+
+//sortedNames returns the keys of attributes sorted,
+//so records are always written in the same order
+func sortedNames(attributes map[string]interface{}) []string {
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//rebind rewrites the ? placeholders of query
+//to the ones of the dialect
+func (r *SQLRepository[T]) rebind(query string) string {
+
+	if r.dialect != Postgres {
+		return query
+	}
+
+	var rebound strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			rebound.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		rebound.WriteRune(c)
+	}
+
+	return rebound.String()
+}
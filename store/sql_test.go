@@ -0,0 +1,60 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+)
+
+// ---- a codec for a plain entity ----
+
+type testEntity struct {
+	id         string
+	from       time.Time
+	until      time.Time
+	attributes map[string]interface{}
+}
+
+func (e *testEntity) IsExistentAt(pit time.Time) bool {
+	return !e.from.After(pit) && (e.until.IsZero() || e.until.After(pit))
+}
+
+func (e *testEntity) ExistentFrom() time.Time {
+	return e.from
+}
+
+func (e *testEntity) ValidUntil() time.Time {
+	return e.until
+}
+
+func (e *testEntity) ActiveDuration() time.Duration {
+	return e.until.Sub(e.from)
+}
+
+type testCodec struct{}
+
+func (testCodec) Encode(e *testEntity) (domain.EntityRecord, error) {
+	return domain.EntityRecord{ID: e.id, Kind: "test", From: e.from, Until: e.until, Attributes: e.attributes}, nil
+}
+
+func (testCodec) Decode(r domain.EntityRecord) (*testEntity, error) {
+	if r.Kind != "test" {
+		return nil, fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	return &testEntity{id: r.ID, from: r.From, until: r.Until, attributes: r.Attributes}, nil
+}
+
+// ------------------ Tests -------
+
+func TestRebind(t *testing.T) {
+
+	query := "SELECT id FROM entities WHERE valid_from < ? AND valid_until > ?"
+	if got := (&SQLRepository[*testEntity]{dialect: SQLite}).rebind(query); got != query {
+		t.Errorf("expected the query unchanged, got %s", got)
+	}
+	if got := (&SQLRepository[*testEntity]{dialect: Postgres}).rebind(query); got != "SELECT id FROM entities WHERE valid_from < $1 AND valid_until > $2" {
+		t.Errorf("unexpected query %s", got)
+	}
+}
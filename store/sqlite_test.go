@@ -0,0 +1,292 @@
+//go:build cgo
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NTsiridis/orgopus/domain"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSQLite opens a new SQLite database holding the tables of Schema
+func openSQLite(t *testing.T) (*sql.DB, *SQLRepository[*testEntity]) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "orgopus.db")+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repository := NewSQLRepository[*testEntity](db, SQLite, testCodec{})
+	// the schema can be created over an existing one
+	for i := 0; i < 2; i++ {
+		if err := repository.CreateSchema(context.Background()); err != nil {
+			t.Fatalf("unexpected error creating the schema %v", err)
+		}
+	}
+	return db, repository
+}
+
+func TestSQLiteSchema(t *testing.T) {
+
+	db, _ := openSQLite(t)
+
+	rows, err := db.Query("SELECT type, name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var kind, name string
+		if err := rows.Scan(&kind, &name); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, kind+" "+name)
+	}
+	expected := []string{"table entities", "index entities_interval", "table entity_attributes", "index entity_attributes_value", "table entity_details"}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, objects)
+	}
+	for i := range expected {
+		if objects[i] != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], objects[i])
+		}
+	}
+
+	// attributes of unknown entities are rejected and
+	// removed along with the entity they belong to
+	if _, err := db.Exec(`INSERT INTO entity_attributes (entity_id, name, value) VALUES ('x', 'grade', '1')`); err == nil {
+		t.Error("expected the attribute of a missing entity to be rejected")
+	}
+	if _, err := db.Exec(`INSERT INTO entities (id, kind, valid_from) VALUES ('x', 'test', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO entity_attributes (entity_id, name, value) VALUES ('x', 'grade', '1')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM entities WHERE id = 'x'`); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entity_attributes").Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected the attributes to be deleted along with the entity, got %d, %v", count, err)
+	}
+}
+
+func TestSQLiteRepository(t *testing.T) {
+
+	_, repository := openSQLite(t)
+	ctx := context.Background()
+
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	ids := func(entities []*testEntity) []string {
+		found := make([]string, len(entities))
+		for i, e := range entities {
+			found[i] = e.id
+		}
+		return found
+	}
+
+	err := repository.Save(ctx,
+		&testEntity{id: "a", from: day(1), attributes: map[string]interface{}{"grade": 7, "name": "Ann"}},
+		&testEntity{id: "b", from: day(1), until: day(3), attributes: map[string]interface{}{"grade": 3}},
+		&testEntity{id: "c", from: day(5), until: day(8)})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cases := []struct {
+		from, to time.Time
+		expected []string
+	}{
+		{day(1), day(2), []string{"a", "b"}},
+		{day(3), day(5), []string{"a"}},
+		{day(4), day(6), []string{"a", "c"}},
+		{day(2), time.Time{}, []string{"a", "b", "c"}},
+		{day(8), time.Time{}, []string{"a"}},
+	}
+	for _, c := range cases {
+		found, err := repository.FindByInterval(ctx, c.from, c.to)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got := ids(found); strings.Join(got, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("[%v, %v): expected %v, got %v", c.from, c.to, c.expected, got)
+		}
+	}
+
+	found, err := repository.FindByAttribute(ctx, "grade", 7)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(found) != 1 || found[0].id != "a" || found[0].attributes["grade"] != 7 || found[0].attributes["name"] != "Ann" {
+		t.Fatalf("unexpected entities %v", found)
+	}
+	if !found[0].from.Equal(day(1)) || !found[0].until.IsZero() {
+		t.Errorf("unexpected interval of %v", found[0])
+	}
+
+	// saving again replaces the stored entity
+	if err := repository.Save(ctx, &testEntity{id: "a", from: day(1), until: day(2), attributes: map[string]interface{}{"grade": 8}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if found, err := repository.FindByAttribute(ctx, "grade", 7); err != nil || len(found) != 0 {
+		t.Errorf("expected the previous attributes to be replaced, got %v, %v", found, err)
+	}
+
+	collection, err := repository.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	entities := collection.Entities()
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %v", entities)
+	}
+	if existent := collection.FindExistentAt(day(2)); len(existent) != 1 || existent[0].id != "b" {
+		t.Errorf("expected only b existent at %v, got %v", day(2), existent)
+	}
+	if a := entities[0]; a.id != "a" || !a.until.Equal(day(2)) || len(a.attributes) != 1 || a.attributes["grade"] != 8 {
+		t.Errorf("unexpected entity %v", a)
+	}
+}
+
+func TestSQLitePitRange(t *testing.T) {
+
+	_, repository := openSQLite(t)
+	ctx := context.Background()
+
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range []*testEntity{
+		{id: "early", from: time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{id: "late", from: from, until: time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		err := repository.Save(ctx, &testEntity{id: "ok", from: from}, e)
+		if !errors.Is(err, ErrPitOutOfRange) {
+			t.Errorf("%s: expected ErrPitOutOfRange, got %v", e.id, err)
+		}
+	}
+	if found, err := repository.FindByInterval(ctx, from, time.Time{}); err != nil || len(found) != 0 {
+		t.Errorf("expected nothing to be saved, got %v, %v", found, err)
+	}
+
+	// searches may reach past the pits that can be stored
+	if err := repository.Save(ctx, &testEntity{id: "ok", from: from}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	found, err := repository.FindByInterval(ctx, time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(found) != 1 {
+		t.Errorf("expected the entity to be found, got %v, %v", found, err)
+	}
+}
+
+func TestSQLiteConsistentReads(t *testing.T) {
+
+	_, repository := openSQLite(t)
+	ctx := context.Background()
+
+	// the attribute of the entity always matches its end
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	save := func(i int) error {
+		return repository.Save(ctx, &testEntity{id: "a", from: from, until: from.AddDate(0, 0, i), attributes: map[string]interface{}{"days": i}})
+	}
+	if err := save(1); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 2; i < 500; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := save(i); err != nil {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+		}
+	}()
+	defer wg.Wait()
+	defer close(stop)
+
+	for i := 0; i < 500; i++ {
+		found, err := repository.FindByInterval(ctx, from, time.Time{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(found) != 1 {
+			t.Fatalf("expected the entity, got %v", found)
+		}
+		days := int(found[0].until.Sub(from) / (24 * time.Hour))
+		if found[0].attributes["days"] != days {
+			t.Fatalf("expected the attributes of the entity ending after %d days, got %v", days, found[0].attributes)
+		}
+	}
+}
+
+func TestSQLiteOrgCodec(t *testing.T) {
+
+	db, _ := openSQLite(t)
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	org, err := domain.NewOrganization("acme", "Acme", day(1), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales, err := domain.NewOrgUnit(org, nil, "sales", "Sales", day(2), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sales.SetAttributeAt("budget", 100, day(2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sales.SetAttributeAt("budget", 150, day(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	repository := NewSQLRepository[domain.TimeTrackedEntity](db, SQLite, domain.NewOrgCodec())
+	if err := repository.Save(ctx, org, sales); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	codec := domain.NewOrgCodec()
+	collection, err := NewSQLRepository[domain.TimeTrackedEntity](db, SQLite, codec).Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if missing := codec.Missing(); len(missing) != 0 {
+		t.Errorf("unexpected missing entities %v", missing)
+	}
+	entities := collection.Entities()
+	if len(entities) != 2 {
+		t.Fatalf("expected 2 entities, got %v", entities)
+	}
+	loaded, ok := entities[1].(*domain.OrgUnit)
+	if !ok || loaded.Name != "Sales" || loaded.Organization() != entities[0] {
+		t.Fatalf("unexpected unit %v", entities[1])
+	}
+	if budget, err := loaded.GetAttributeAt("budget", day(3)); budget != 100 || err != nil {
+		t.Errorf("expected the budget in effect then, got %v, %v", budget, err)
+	}
+	if history := loaded.AttributeHistory("budget"); len(history) != 2 {
+		t.Errorf("expected the history of the budget, got %v", history)
+	}
+}